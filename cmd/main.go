@@ -1,45 +1,273 @@
 package main
 
 import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/jackc/pgconn"
+	"github.com/levtul/tmp/config"
 	"github.com/levtul/tmp/db"
 	"github.com/levtul/tmp/domain"
+	"github.com/levtul/tmp/model"
+	"github.com/levtul/tmp/walker"
 	"log"
+	"net"
 	"os"
+	"os/signal"
+	"runtime"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
 )
 
+const usage = "Usage: ./pg_gen [--config <config_file>] [--diagnostics text|json] [--tx table|single|savepoint] [--deferred] [--jobs N] [--progress=false] [--stats text|json] [--stats-file <file>] [--timeout <duration>] [--append | --truncate | --delete [--yes]] <filename|migrations_dir> <connection_string>"
+
 func main() {
+	configPath := flag.String("config", "", "YAML or JSON file with generation settings")
+	diagnosticsFormat := flag.String("diagnostics", "text", "format of schema errors and warnings: text or json")
+	appendRows := flag.Bool("append", false, "keep existing rows, reference them by foreign keys and keep keys unique")
+	truncate := flag.Bool("truncate", false, "empty the tables by TRUNCATE ... RESTART IDENTITY before filling")
+	deleteRows := flag.Bool("delete", false, "delete rows of the tables before filling")
+	yes := flag.Bool("yes", false, "do not ask for confirmation to clean tables of a database on a remote host")
+	tx := flag.String("tx", walker.TxTable, "transaction mode: table, single or savepoint, cleaning uses single by default")
+	deferred := flag.Bool("deferred", false, "run SET CONSTRAINTS ALL DEFERRED before filling")
+	jobs := flag.Int("jobs", runtime.NumCPU(), "number of tables filled at once, savepoint mode fills one")
+	progress := flag.Bool("progress", true, "log progress of the tables to stderr")
+	statsFormat := flag.String("stats", "text", "format of the table statistics: text or json")
+	statsFile := flag.String("stats-file", "", "file to write the table statistics to instead of stdout or stderr")
+	timeout := flag.Duration("timeout", 0, "limit of the connection and filling time, e.g. 10m")
+	flag.Parse()
+
+	if *diagnosticsFormat != "text" && *diagnosticsFormat != "json" {
+		log.Fatalln(usage)
+	}
+	if *tx != walker.TxSingle && *tx != walker.TxSavepoint && *tx != walker.TxTable || *jobs < 1 ||
+		*statsFormat != "text" && *statsFormat != "json" {
+		log.Fatalln(usage)
+	}
+	if *statsFormat == "json" && *diagnosticsFormat == "json" && *statsFile == "" {
+		log.Fatalln("--stats json and --diagnostics json both write to stdout, use --stats-file")
+	}
+	options := walker.FillOptions{Append: *appendRows, Transaction: *tx, Deferred: *deferred, Jobs: *jobs}
+	switch {
+	case *truncate && *deleteRows, (*truncate || *deleteRows) && *appendRows:
+		log.Fatalln(usage)
+	case *truncate:
+		options.Clean = walker.CleanTruncate
+	case *deleteRows:
+		options.Clean = walker.CleanDelete
+	}
+	if options.Clean != "" && *tx == walker.TxTable {
+		if isFlagSet("tx") {
+			log.Fatalln("--truncate and --delete clean tables in one transaction with the fill, they cannot be used with --tx table")
+		}
+		options.Transaction = walker.TxSingle
+	}
+	if *tx == walker.TxSavepoint && options.Jobs > 1 {
+		if isFlagSet("jobs") {
+			log.Fatalln("--tx savepoint fills one table at a time, it cannot be used with --jobs greater than 1")
+		}
+		options.Jobs = 1
+	}
+
 	// get filename & connection string from args
-	if len(os.Args) < 3 {
-		log.Fatalln("Usage: ./pg_gen <filename> <connection_string>")
+	if flag.NArg() < 2 {
+		log.Fatalln(usage)
 	}
 
-	// get filename
-	filename := os.Args[1]
+	// get schema file or migrations directory
+	filename := flag.Arg(0)
 
 	// get connection string
-	connectionString := os.Args[2]
+	connectionString := flag.Arg(1)
 
-	sql, err := domain.RunFormatter(filename)
+	sources, err := domain.ReadSources(filename)
 	if err != nil {
-		log.Fatalf("domain.RunFormatter: %s", err.Error())
+		log.Fatalf("domain.ReadSources: %s", err.Error())
 	}
 
-	myWalker, err := domain.Walk(sql)
-	if err != nil {
+	myWalker, err := domain.Walk(sources...)
+	var diagnostics model.Diagnostics
+	if errors.As(err, &diagnostics) {
+		printDiagnostics(diagnostics, *diagnosticsFormat)
+		os.Exit(1)
+	} else if err != nil {
 		log.Fatalf("domain.Walk: %s", err.Error())
 	}
+	if len(myWalker.Warnings) > 0 || *diagnosticsFormat == "json" {
+		printDiagnostics(myWalker.Warnings, *diagnosticsFormat)
+	}
+
+	if *configPath != "" {
+		cfg, err := config.Load(*configPath)
+		if err != nil {
+			log.Fatalf("config.Load: %s", err.Error())
+		}
+
+		err = cfg.Apply(myWalker.Schemas)
+		if err != nil {
+			log.Fatalf("config.Apply: %s", err.Error())
+		}
+	}
+
+	// on SIGINT or SIGTERM the fill stops and its transaction is rolled back
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if *timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *timeout)
+		defer cancel()
+	}
 
-	dbPool, err := db.Connect(connectionString)
+	dbPool, err := db.Connect(ctx, connectionString)
 	if err != nil {
 		log.Fatalf("db.Connect: %s", err.Error())
 	}
 	defer dbPool.Close()
 
-	err = myWalker.FillAllDB(dbPool)
+	if options.Clean != "" && !*yes && !confirmClean(ctx, dbPool.Config().ConnConfig.Config, options.Clean) {
+		log.Fatalln("cleaning is not confirmed")
+	}
+
+	if *progress {
+		options.Progress = logProgress()
+	}
+
+	stats, err := myWalker.FillAllDB(ctx, dbPool, options)
+	if err != nil && ctx.Err() != nil {
+		if options.Transaction == walker.TxTable {
+			log.Fatalf("filling is stopped, the table being filled is rolled back, tables committed before stay: %s", ctx.Err())
+		}
+		log.Fatalf("filling is stopped, its transaction is rolled back, the database is left untouched: %s", ctx.Err())
+	}
 	if err != nil {
 		log.Fatalf("error: %s", err)
 	}
 
 	log.Println("Successfully generated data!")
+	if err := printStats(stats, *statsFormat, *statsFile); err != nil {
+		log.Fatalf("printStats: %s", err.Error())
+	}
 	return
 }
+
+// isFlagSet reports whether the flag is given on the command line.
+func isFlagSet(name string) bool {
+	set := false
+	flag.Visit(func(f *flag.Flag) {
+		set = set || f.Name == name
+	})
+
+	return set
+}
+
+// confirmClean asks to type the database name before cleaning tables of
+// a database that is not on the local host.
+func confirmClean(ctx context.Context, cfg pgconn.Config, mode string) bool {
+	if isLocalHost(cfg.Host) {
+		return true
+	}
+
+	fmt.Fprintf(os.Stderr, "Tables of database %s on %s will be cleaned by %s. Type the database name to continue: ",
+		cfg.Database, cfg.Host, strings.ToUpper(mode))
+	answers := make(chan string, 1)
+	go func() {
+		answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+		answers <- answer
+	}()
+
+	select {
+	case answer := <-answers:
+		return strings.TrimSpace(answer) == cfg.Database
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// isLocalHost reports whether the host is a loopback address or a unix socket.
+func isLocalHost(host string) bool {
+	if host == "localhost" || strings.HasPrefix(host, "/") {
+		return true
+	}
+	ip := net.ParseIP(host)
+
+	return ip != nil && ip.IsLoopback()
+}
+
+// logProgress returns a progress callback logging the table being filled
+// at most once a second and every filled table.
+func logProgress() func(stats walker.TableStats) {
+	var last time.Time
+	return func(s walker.TableStats) {
+		done := s.Inserted >= s.Rows
+		if !done && time.Since(last) < time.Second {
+			return
+		}
+		last = time.Now()
+
+		if done {
+			log.Printf("%s.%s: %d rows inserted in %s", s.Schema, s.Table, s.Inserted, s.Duration.Round(time.Millisecond))
+			return
+		}
+		log.Printf("%s.%s: %d/%d rows generated, %d inserted, %.0f rows/s, ETA %s",
+			s.Schema, s.Table, s.Generated, s.Rows, s.Inserted, s.RowsPerSecond(), s.ETA().Round(time.Second))
+	}
+}
+
+// printStats writes the table statistics as a table to stderr or as
+// a JSON array to stdout, or to the file if it is set.
+func printStats(stats []walker.TableStats, format, filename string) error {
+	out := os.Stderr
+	if format == "json" {
+		out = os.Stdout
+	}
+	if filename != "" {
+		f, err := os.Create(filename)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		out = f
+	}
+
+	if format == "json" {
+		data, err := json.MarshalIndent(stats, "", "  ")
+		if err != nil {
+			return fmt.Errorf("json.Marshal: %w", err)
+		}
+		_, err = fmt.Fprintln(out, string(data))
+		return err
+	}
+
+	tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "table\trows\tretries\tduration\trows/s\t")
+	for _, s := range stats {
+		fmt.Fprintf(tw, "%s.%s\t%d\t%d\t%s\t%.0f\t\n",
+			s.Schema, s.Table, s.Inserted, s.Retries, s.Duration.Round(time.Millisecond), s.RowsPerSecond())
+	}
+	return tw.Flush()
+}
+
+// printDiagnostics writes diagnostics compiler-style to stderr or as
+// a JSON array to stdout.
+func printDiagnostics(diagnostics model.Diagnostics, format string) {
+	if format == "json" {
+		if diagnostics == nil {
+			diagnostics = model.Diagnostics{}
+		}
+		data, err := json.MarshalIndent(diagnostics, "", "  ")
+		if err != nil {
+			log.Fatalf("json.Marshal: %s", err.Error())
+		}
+		fmt.Println(string(data))
+		return
+	}
+
+	for _, d := range diagnostics {
+		fmt.Fprintln(os.Stderr, d.Error())
+	}
+}
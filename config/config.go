@@ -0,0 +1,269 @@
+package config
+
+import (
+	"fmt"
+	"github.com/levtul/tmp/model"
+	"gopkg.in/yaml.v3"
+	"os"
+	"strings"
+)
+
+const (
+	// ModeMerge applies config settings over the settings from SQL comments.
+	ModeMerge = "merge"
+	// ModeOverride ignores settings from SQL comments completely.
+	ModeOverride = "override"
+)
+
+// Config holds generation settings that are kept outside the SQL files.
+// It is read from YAML, JSON is accepted as well.
+//
+//	mode: merge
+//	tables:
+//	  public.users:
+//	    count: 1000
+//	    rules: ["updated_at >= created_at"]
+//	  public.user_roles:
+//	    fanout: "[1 - 3]"
+//	columns:
+//	  public.users.name:
+//	    generator: type:name_ru
+//	  public.users.middle_name:
+//	    null_ratio: 0.3
+//	foreign_keys:
+//	  public.orders.orders_customer_id_fkey:
+//	    skew: 1.5
+type Config struct {
+	Mode        string                 `yaml:"mode"`
+	Tables      map[string]*Table      `yaml:"tables"`
+	Columns     map[string]*Column     `yaml:"columns"`
+	ForeignKeys map[string]*ForeignKey `yaml:"foreign_keys"`
+}
+
+type Table struct {
+	Count   *int     `yaml:"count"`
+	Rules   []string `yaml:"rules"`
+	Density *float64 `yaml:"density"`
+	FanOut  *string  `yaml:"fanout"`
+}
+
+type Column struct {
+	Generator *string  `yaml:"generator"`
+	NullRatio *float64 `yaml:"null_ratio"`
+}
+
+type ForeignKey struct {
+	NullRatio *float64 `yaml:"null_ratio"`
+	Skew      *float64 `yaml:"skew"`
+}
+
+func Load(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read config %s: os.ReadFile: %w", path, err)
+	}
+
+	c := &Config{}
+	if err := yaml.Unmarshal(data, c); err != nil {
+		return nil, fmt.Errorf("cannot parse config %s: %w", path, err)
+	}
+
+	switch c.Mode {
+	case "":
+		c.Mode = ModeMerge
+	case ModeMerge, ModeOverride:
+	default:
+		return nil, fmt.Errorf("invalid config mode: %s, expected %s or %s", c.Mode, ModeMerge, ModeOverride)
+	}
+
+	return c, nil
+}
+
+// Apply sets generation settings of the walked schemas according to the config.
+func (c *Config) Apply(schemas map[string]*model.Schema) error {
+	if c.Mode == ModeOverride {
+		for _, schema := range schemas {
+			for _, table := range schema.Tables {
+				table.TableGenerationSettings = &model.TableGenerationSettings{RowsCount: model.DefaultRowsCount}
+				for _, column := range table.Columns {
+					column.GenerationType = nil
+					column.NullRatio = 0
+					column.Skew = 0
+				}
+				for _, fk := range table.ForeignKeyConstraints {
+					fk.NullRatio = 0
+					fk.Skew = 0
+				}
+			}
+		}
+	}
+
+	for key, settings := range c.Tables {
+		table, err := findTable(schemas, strings.Split(key, "."))
+		if err != nil {
+			return fmt.Errorf("config tables.%s: %w", key, err)
+		}
+
+		if err := settings.apply(table); err != nil {
+			return fmt.Errorf("config tables.%s: %w", key, err)
+		}
+	}
+
+	for key, settings := range c.Columns {
+		parts := strings.Split(key, ".")
+		if len(parts) < 2 {
+			return fmt.Errorf("config columns.%s: expected schema.table.column", key)
+		}
+
+		table, err := findTable(schemas, parts[:len(parts)-1])
+		if err != nil {
+			return fmt.Errorf("config columns.%s: %w", key, err)
+		}
+		column, ok := table.Columns[parts[len(parts)-1]]
+		if !ok {
+			return fmt.Errorf("config columns.%s: column %s not found", key, parts[len(parts)-1])
+		}
+
+		if err := settings.apply(table, column); err != nil {
+			return fmt.Errorf("config columns.%s: %w", key, err)
+		}
+	}
+
+	for key, settings := range c.ForeignKeys {
+		parts := strings.Split(key, ".")
+		if len(parts) < 2 {
+			return fmt.Errorf("config foreign_keys.%s: expected schema.table.foreign_key", key)
+		}
+
+		table, err := findTable(schemas, parts[:len(parts)-1])
+		if err != nil {
+			return fmt.Errorf("config foreign_keys.%s: %w", key, err)
+		}
+		var fk *model.ForeignKeyConstraint
+		for _, f := range table.ForeignKeyConstraints {
+			if f.Name == parts[len(parts)-1] {
+				fk = f
+			}
+		}
+		if fk == nil {
+			return fmt.Errorf("config foreign_keys.%s: foreign key %s not found", key, parts[len(parts)-1])
+		}
+
+		if err := settings.apply(table, fk); err != nil {
+			return fmt.Errorf("config foreign_keys.%s: %w", key, err)
+		}
+	}
+
+	return nil
+}
+
+func findTable(schemas map[string]*model.Schema, parts []string) (*model.Table, error) {
+	s, name := "public", parts[len(parts)-1]
+	switch len(parts) {
+	case 1:
+	case 2:
+		s = parts[0]
+	default:
+		return nil, fmt.Errorf("expected schema.table")
+	}
+
+	schema, ok := schemas[s]
+	if !ok {
+		return nil, fmt.Errorf("schema %s not found", s)
+	}
+	table, ok := schema.Tables[name]
+	if !ok {
+		return nil, fmt.Errorf("table %s.%s not found", s, name)
+	}
+
+	return table, nil
+}
+
+func (t *Table) apply(table *model.Table) error {
+	if table.TableGenerationSettings == nil {
+		table.TableGenerationSettings = &model.TableGenerationSettings{RowsCount: model.DefaultRowsCount}
+	}
+	settings := table.TableGenerationSettings
+
+	if t.Count != nil {
+		if *t.Count < 0 {
+			return fmt.Errorf("invalid count: %d", *t.Count)
+		}
+		settings.RowsCount = *t.Count
+	}
+
+	if t.Rules != nil {
+		settings.Rules = nil
+		for _, r := range t.Rules {
+			rule, err := model.NewRowRuleFromString(r)
+			if err != nil {
+				return err
+			}
+			if err := rule.ValidateTable(table); err != nil {
+				return err
+			}
+			settings.Rules = append(settings.Rules, rule)
+		}
+	}
+
+	if t.Density != nil {
+		if err := model.CheckDensity(*t.Density); err != nil {
+			return err
+		}
+		settings.Density = *t.Density
+	}
+	if t.FanOut != nil {
+		fanOut, err := model.ParseFanOut(*t.FanOut)
+		if err != nil {
+			return err
+		}
+		settings.FanOut = fanOut
+	}
+
+	return nil
+}
+
+func (c *Column) apply(table *model.Table, column *model.Column) error {
+	if c.Generator != nil {
+		gt, err := model.NewGenerationTypeFromString(*c.Generator, column.Type)
+		if err != nil {
+			return err
+		}
+		column.GenerationType = *gt
+	}
+
+	if c.NullRatio != nil {
+		if *c.NullRatio < 0 || *c.NullRatio > 1 {
+			return fmt.Errorf("invalid null_ratio: %v, expected value from 0 to 1", *c.NullRatio)
+		}
+		if *c.NullRatio > 0 && !column.Nullable(table) {
+			return fmt.Errorf("column %s is not nullable", column.Name)
+		}
+		column.NullRatio = *c.NullRatio
+	}
+
+	return nil
+}
+
+func (f *ForeignKey) apply(table *model.Table, fk *model.ForeignKeyConstraint) error {
+	if f.NullRatio != nil {
+		if *f.NullRatio < 0 || *f.NullRatio > 1 {
+			return fmt.Errorf("invalid null_ratio: %v, expected value from 0 to 1", *f.NullRatio)
+		}
+		for _, name := range fk.Columns {
+			if *f.NullRatio > 0 && !table.Columns[name].Nullable(table) {
+				return fmt.Errorf("column %s is not nullable", name)
+			}
+		}
+		fk.NullRatio = *f.NullRatio
+	}
+
+	if f.Skew != nil {
+		if err := model.CheckSkew(*f.Skew); err != nil {
+			return err
+		}
+		fk.Skew = *f.Skew
+	}
+
+	return nil
+}
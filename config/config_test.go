@@ -0,0 +1,217 @@
+package config
+
+import (
+	"github.com/auxten/postgresql-parser/pkg/sql/types"
+	"github.com/levtul/tmp/model"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func testSchemas() map[string]*model.Schema {
+	users := &model.Table{
+		Schema:     "public",
+		Name:       "users",
+		PrimaryKey: []string{"id"},
+		Columns: map[string]*model.Column{
+			"id":         {Name: "id", Type: types.Int},
+			"name":       {Name: "name", Type: types.String, NotNull: true},
+			"nick":       {Name: "nick", Type: types.String},
+			"created_at": {Name: "created_at", Type: types.Timestamp},
+			"updated_at": {Name: "updated_at", Type: types.Timestamp},
+		},
+	}
+	gt, _ := model.NewGenerationTypeFromString("type:name", types.String)
+	users.Columns["nick"].GenerationType = *gt
+	users.Columns["nick"].NullRatio = 0.5
+	users.TableGenerationSettings = &model.TableGenerationSettings{RowsCount: 5}
+
+	orders := &model.Table{
+		Schema: "public",
+		Name:   "orders",
+		Columns: map[string]*model.Column{
+			"id":          {Name: "id", Type: types.Int, NotNull: true},
+			"customer_id": {Name: "customer_id", Type: types.Int},
+		},
+		ForeignKeyConstraints: []*model.ForeignKeyConstraint{{
+			Name:      "orders_customer_id_fkey",
+			Columns:   []string{"customer_id"},
+			Ref:       &model.ForeignKeyRef{Table: users, Columns: []string{"id"}},
+			NullRatio: 0.1,
+			Skew:      2,
+		}},
+	}
+
+	logs := &model.Table{
+		Schema:  "audit",
+		Name:    "logs",
+		Columns: map[string]*model.Column{"message": {Name: "message", Type: types.String}},
+	}
+
+	return map[string]*model.Schema{
+		"public": {Name: "public", Tables: map[string]*model.Table{"users": users, "orders": orders}},
+		"audit":  {Name: "audit", Tables: map[string]*model.Table{"logs": logs}},
+	}
+}
+
+func writeConfig(t *testing.T, name, data string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		mode string
+		err  bool
+	}{
+		{name: "c.yaml", data: "tables:\n  users:\n    count: 3\n", mode: ModeMerge},
+		{name: "c.yaml", data: "mode: override\n", mode: ModeOverride},
+		{name: "c.json", data: `{"mode": "merge", "tables": {"users": {"count": 3}}}`, mode: ModeMerge},
+		{name: "c.yaml", data: "mode: replace\n", err: true},
+		{name: "c.yaml", data: "tables: [\n", err: true},
+	}
+
+	for _, tt := range tests {
+		c, err := Load(writeConfig(t, tt.name, tt.data))
+		if (err != nil) != tt.err {
+			t.Errorf("Load(%q) error = %v, want error %v", tt.data, err, tt.err)
+			continue
+		}
+		if err == nil && c.Mode != tt.mode {
+			t.Errorf("Load(%q) mode = %s, want %s", tt.data, c.Mode, tt.mode)
+		}
+	}
+
+	if _, err := Load(filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+		t.Errorf("Load of a missing file succeeded")
+	}
+}
+
+func TestApplyMode(t *testing.T) {
+	tests := []struct {
+		mode      string
+		count     int
+		generator bool
+		nullRatio float64
+		fkSkew    float64
+	}{
+		{mode: ModeMerge, count: 5, generator: true, nullRatio: 0.5, fkSkew: 2},
+		{mode: ModeOverride, count: model.DefaultRowsCount, generator: false, nullRatio: 0, fkSkew: 0},
+	}
+
+	for _, tt := range tests {
+		schemas := testSchemas()
+		c := &Config{Mode: tt.mode}
+		if err := c.Apply(schemas); err != nil {
+			t.Fatalf("%s: Apply() error = %v", tt.mode, err)
+		}
+
+		users := schemas["public"].Tables["users"]
+		if users.TableGenerationSettings.RowsCount != tt.count {
+			t.Errorf("%s: count = %d, want %d", tt.mode, users.TableGenerationSettings.RowsCount, tt.count)
+		}
+		nick := users.Columns["nick"]
+		if (nick.GenerationType != nil) != tt.generator {
+			t.Errorf("%s: generator = %v, want set %v", tt.mode, nick.GenerationType, tt.generator)
+		}
+		if nick.NullRatio != tt.nullRatio {
+			t.Errorf("%s: null_ratio = %v, want %v", tt.mode, nick.NullRatio, tt.nullRatio)
+		}
+		fk := schemas["public"].Tables["orders"].ForeignKeyConstraints[0]
+		if fk.Skew != tt.fkSkew || (tt.fkSkew == 0) != (fk.NullRatio == 0) {
+			t.Errorf("%s: foreign key skew = %v, null_ratio = %v, want skew %v", tt.mode, fk.Skew, fk.NullRatio, tt.fkSkew)
+		}
+	}
+}
+
+func TestApplyKeys(t *testing.T) {
+	count := 7
+	ratio := 0.2
+	generator := "type:word"
+	skew := 1.5
+	c := &Config{
+		Mode: ModeMerge,
+		Tables: map[string]*Table{
+			"users":      {Count: &count, Rules: []string{"updated_at >= created_at"}},
+			"audit.logs": {Count: &count},
+		},
+		Columns: map[string]*Column{
+			"users.nick":         {NullRatio: &ratio},
+			"audit.logs.message": {Generator: &generator},
+		},
+		ForeignKeys: map[string]*ForeignKey{
+			"public.orders.orders_customer_id_fkey": {Skew: &skew},
+		},
+	}
+
+	schemas := testSchemas()
+	if err := c.Apply(schemas); err != nil {
+		t.Fatalf("Apply() error = %v", err)
+	}
+
+	users := schemas["public"].Tables["users"]
+	if users.TableGenerationSettings.RowsCount != count || len(users.TableGenerationSettings.Rules) != 1 {
+		t.Errorf("users settings = %+v", users.TableGenerationSettings)
+	}
+	if users.Columns["nick"].NullRatio != ratio || users.Columns["nick"].GenerationType == nil {
+		t.Errorf("users.nick = %+v, want null_ratio %v and the generator kept", users.Columns["nick"], ratio)
+	}
+
+	fk := schemas["public"].Tables["orders"].ForeignKeyConstraints[0]
+	if fk.Skew != skew || fk.NullRatio != 0.1 {
+		t.Errorf("orders_customer_id_fkey skew = %v, null_ratio = %v, want %v and 0.1 kept", fk.Skew, fk.NullRatio, skew)
+	}
+
+	logs := schemas["audit"].Tables["logs"]
+	if logs.TableGenerationSettings.RowsCount != count {
+		t.Errorf("audit.logs count = %d, want %d", logs.TableGenerationSettings.RowsCount, count)
+	}
+	if logs.Columns["message"].GenerationType == nil {
+		t.Errorf("audit.logs.message generator is not set")
+	}
+}
+
+func TestApplyErrors(t *testing.T) {
+	negative := -1
+	ratio := 0.5
+	badRatio := 1.5
+	badGenerator := "range:[1 - 10]"
+	badSkew := 1.0
+	tests := []struct {
+		name        string
+		tables      map[string]*Table
+		columns     map[string]*Column
+		foreignKeys map[string]*ForeignKey
+	}{
+		{name: "unknown table", tables: map[string]*Table{"payments": {}}},
+		{name: "unknown schema", tables: map[string]*Table{"sales.users": {}}},
+		{name: "too many parts", tables: map[string]*Table{"db.public.users": {}}},
+		{name: "negative count", tables: map[string]*Table{"users": {Count: &negative}}},
+		{name: "invalid rule", tables: map[string]*Table{"users": {Rules: []string{"updated_at => created_at"}}}},
+		{name: "rule on unknown column", tables: map[string]*Table{"users": {Rules: []string{"deleted_at > created_at"}}}},
+		{name: "column without table", columns: map[string]*Column{"nick": {}}},
+		{name: "unknown column", columns: map[string]*Column{"users.email": {}}},
+		{name: "invalid null_ratio", columns: map[string]*Column{"users.nick": {NullRatio: &badRatio}}},
+		{name: "null_ratio of not null", columns: map[string]*Column{"users.name": {NullRatio: &ratio}}},
+		{name: "null_ratio of primary key", columns: map[string]*Column{"users.id": {NullRatio: &ratio}}},
+		{name: "generator of other type", columns: map[string]*Column{"users.name": {Generator: &badGenerator}}},
+		{name: "foreign key without table", foreignKeys: map[string]*ForeignKey{"orders_customer_id_fkey": {}}},
+		{name: "unknown foreign key", foreignKeys: map[string]*ForeignKey{"orders.orders_user_id_fkey": {}}},
+		{name: "invalid skew", foreignKeys: map[string]*ForeignKey{"orders.orders_customer_id_fkey": {Skew: &badSkew}}},
+		{name: "invalid foreign key null_ratio", foreignKeys: map[string]*ForeignKey{"orders.orders_customer_id_fkey": {NullRatio: &badRatio}}},
+	}
+
+	for _, tt := range tests {
+		c := &Config{Mode: ModeMerge, Tables: tt.tables, Columns: tt.columns, ForeignKeys: tt.foreignKeys}
+		if err := c.Apply(testSchemas()); err == nil {
+			t.Errorf("%s: Apply() succeeded, want error", tt.name)
+		}
+	}
+}
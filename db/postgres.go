@@ -6,17 +6,17 @@ import (
 	"github.com/jackc/pgx/v4/pgxpool"
 )
 
-func Connect(connectionString string) (*pgxpool.Pool, error) {
+func Connect(ctx context.Context, connectionString string) (*pgxpool.Pool, error) {
 	// connect to postgresql database
 	databaseUrl := connectionString
 
 	// this returns connection pool
-	dbPool, err := pgxpool.Connect(context.Background(), databaseUrl)
+	dbPool, err := pgxpool.Connect(ctx, databaseUrl)
 	if err != nil {
 		return nil, fmt.Errorf("Unable to connect to database: %v\n", err)
 	}
 
-	err = dbPool.Ping(context.Background())
+	err = dbPool.Ping(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("Unable to ping database: %v\n", err)
 	}
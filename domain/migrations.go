@@ -0,0 +1,202 @@
+package domain
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var (
+	// golang-migrate: 1_init.up.sql, 1_init.down.sql
+	migrateReg = regexp.MustCompile(`^(\d+)_.*\.(up|down)\.sql$`)
+	// Flyway: V1.2__init.sql, U1.2__init.sql, R__views.sql
+	flywayReg           = regexp.MustCompile(`^([VU])(\d+(?:[._]\d+)*)__.*\.sql$`)
+	flywayVersionSepReg = regexp.MustCompile(`[._]`)
+	// Flyway repeatable migrations run after the versioned ones
+	flywayRepeatableReg = regexp.MustCompile(`^R__(.*)\.sql$`)
+	// goose and other tools: 20230102150405_init.sql, 001-init.sql
+	versionReg = regexp.MustCompile(`^(\d+)[_\-.]?.*\.sql$`)
+
+	gooseUpReg   = regexp.MustCompile(`(?im)^\s*--\s*\+goose\s+up\b.*$`)
+	gooseDownReg = regexp.MustCompile(`(?im)^\s*--\s*\+goose\s+down\b.*$`)
+)
+
+type migrationKind int
+
+const (
+	migrationVersioned migrationKind = iota
+	migrationUnversioned
+	migrationRepeatable
+)
+
+type migration struct {
+	kind    migrationKind
+	version []string
+	key     string
+	path    string
+}
+
+// ReadSources reads a schema file, or all up-migrations of a directory.
+func ReadSources(path string) ([]Source, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read %s: os.Stat: %w", path, err)
+	}
+	if !info.IsDir() {
+		source, err := ReadSource(path)
+		if err != nil {
+			return nil, err
+		}
+		return []Source{source}, nil
+	}
+
+	return ReadMigrations(path)
+}
+
+// ReadMigrations reads up-migrations of the directory in the order they are
+// applied. golang-migrate, goose, Flyway and Liquibase formatted SQL naming
+// is supported: down and undo migrations are skipped, goose files are cut to
+// their Up sections, Flyway repeatable migrations go after versioned ones,
+// files without a version are ordered by name.
+func ReadMigrations(dir string) ([]Source, error) {
+	var migrations []*migration
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !strings.HasSuffix(d.Name(), ".sql") {
+			return nil
+		}
+
+		if m := parseMigrationName(d.Name()); m != nil {
+			m.path = path
+			migrations = append(migrations, m)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("cannot read migrations from %s: %w", dir, err)
+	}
+
+	sort.SliceStable(migrations, func(i, j int) bool {
+		a, b := migrations[i], migrations[j]
+		if a.kind != b.kind {
+			return a.kind < b.kind
+		}
+		if c := compareVersions(a.version, b.version); c != 0 {
+			return c < 0
+		}
+		return a.key < b.key
+	})
+
+	sources := make([]Source, 0, len(migrations))
+	for _, m := range migrations {
+		source, err := ReadSource(m.path)
+		if err != nil {
+			return nil, err
+		}
+		source.Text = gooseUp(source.Text)
+		sources = append(sources, source)
+	}
+
+	return sources, nil
+}
+
+// parseMigrationName returns nil for down migrations.
+func parseMigrationName(name string) *migration {
+	if m := migrateReg.FindStringSubmatch(name); m != nil {
+		if m[2] == "down" {
+			return nil
+		}
+		return &migration{kind: migrationVersioned, version: []string{m[1]}, key: name}
+	}
+	if m := flywayReg.FindStringSubmatch(name); m != nil {
+		if m[1] == "U" {
+			return nil
+		}
+		return &migration{kind: migrationVersioned, version: flywayVersionSepReg.Split(m[2], -1), key: name}
+	}
+	if m := flywayRepeatableReg.FindStringSubmatch(name); m != nil {
+		return &migration{kind: migrationRepeatable, key: m[1]}
+	}
+	if strings.HasSuffix(name, ".down.sql") {
+		return nil
+	}
+	if m := versionReg.FindStringSubmatch(name); m != nil {
+		return &migration{kind: migrationVersioned, version: []string{m[1]}, key: name}
+	}
+
+	return &migration{kind: migrationUnversioned, key: name}
+}
+
+// compareVersions compares versions made of numeric parts of any length.
+func compareVersions(a, b []string) int {
+	for i := 0; i < len(a) || i < len(b); i++ {
+		x, y := "0", "0"
+		if i < len(a) {
+			x = strings.TrimLeft(a[i], "0")
+		}
+		if i < len(b) {
+			y = strings.TrimLeft(b[i], "0")
+		}
+		if len(x) != len(y) {
+			if len(x) < len(y) {
+				return -1
+			}
+			return 1
+		}
+		if c := strings.Compare(x, y); c != 0 {
+			return c
+		}
+	}
+
+	return 0
+}
+
+// gooseUp blanks out everything but the Up sections of a goose migration,
+// keeping line numbers of the rest. Other files are returned as is.
+func gooseUp(text string) string {
+	ups := gooseUpReg.FindAllStringIndex(text, -1)
+	if ups == nil {
+		return text
+	}
+	downs := gooseDownReg.FindAllStringIndex(text, -1)
+
+	var res strings.Builder
+	pos := 0
+	for _, up := range ups {
+		if up[0] < pos {
+			continue
+		}
+		res.WriteString(blank(text[pos:up[1]]))
+		pos = up[1]
+
+		end := len(text)
+		for _, down := range downs {
+			if down[0] > up[1] {
+				end = down[0]
+				break
+			}
+		}
+		// next Up section, if it comes before Down
+		for _, next := range ups {
+			if next[0] > up[1] && next[0] < end {
+				end = next[0]
+				break
+			}
+		}
+		res.WriteString(text[pos:end])
+		pos = end
+	}
+	res.WriteString(blank(text[pos:]))
+
+	return res.String()
+}
+
+func blank(s string) string {
+	return strings.Repeat("\n", strings.Count(s, "\n"))
+}
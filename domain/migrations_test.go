@@ -0,0 +1,107 @@
+package domain
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadMigrations(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		want  []string
+	}{
+		{
+			name: "golang-migrate",
+			files: map[string]string{
+				"000010_c.up.sql":   "c",
+				"000002_b.up.sql":   "b",
+				"000002_b.down.sql": "-b",
+				"000001_a.up.sql":   "a",
+				"000001_a.down.sql": "-a",
+				"README.md":         "",
+			},
+			want: []string{"000001_a.up.sql", "000002_b.up.sql", "000010_c.up.sql"},
+		},
+		{
+			name: "flyway",
+			files: map[string]string{
+				"R__views.sql":     "views",
+				"V1.10__c.sql":     "c",
+				"V1.2__b.sql":      "b",
+				"V1__a.sql":        "a",
+				"U1.2__b.sql":      "-b",
+				"R__functions.sql": "functions",
+			},
+			want: []string{"V1__a.sql", "V1.2__b.sql", "V1.10__c.sql", "R__functions.sql", "R__views.sql"},
+		},
+		{
+			name: "goose",
+			files: map[string]string{
+				"20230102150405_b.sql": "b",
+				"9_a.sql":              "a",
+			},
+			want: []string{"9_a.sql", "20230102150405_b.sql"},
+		},
+		{
+			name: "unversioned",
+			files: map[string]string{
+				"b.sql":      "b",
+				"a.sql":      "a",
+				"1_v.sql":    "v",
+				"c.md":       "",
+				"x.down.sql": "-x",
+			},
+			want: []string{"1_v.sql", "a.sql", "b.sql"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for name, text := range tt.files {
+				if err := os.WriteFile(filepath.Join(dir, name), []byte(text), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			sources, err := ReadMigrations(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, source := range sources {
+				got = append(got, filepath.Base(source.File))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ReadMigrations() = %q, expected %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadMigrationsGooseUp(t *testing.T) {
+	dir := t.TempDir()
+	text := "-- +goose Up\nCREATE TABLE a (id INT);\n-- +goose Down\nDROP TABLE a;\n"
+	if err := os.WriteFile(filepath.Join(dir, "1_a.sql"), []byte(text), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	sources, err := ReadMigrations(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sources) != 1 {
+		t.Fatalf("ReadMigrations() returned %d sources, expected 1", len(sources))
+	}
+	got := sources[0].Text
+	if !strings.Contains(got, "CREATE TABLE a") || strings.Contains(got, "DROP TABLE") {
+		t.Errorf("goose Up section is not cut: %q", got)
+	}
+	if strings.Count(got, "\n") != strings.Count(text, "\n") {
+		t.Errorf("line numbers are not kept: %q", got)
+	}
+}
@@ -0,0 +1,21 @@
+package domain
+
+import (
+	"fmt"
+	"os"
+)
+
+// Source is the SQL text of one schema file.
+type Source struct {
+	File string
+	Text string
+}
+
+func ReadSource(filename string) (Source, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return Source{}, fmt.Errorf("cannot read file %s: os.ReadFile: %w", filename, err)
+	}
+
+	return Source{File: filename, Text: string(data)}, nil
+}
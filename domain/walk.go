@@ -4,49 +4,101 @@ import (
 	"fmt"
 	"github.com/auxten/postgresql-parser/pkg/sql/parser"
 	"github.com/auxten/postgresql-parser/pkg/walk"
+	"github.com/cockroachdb/errors"
 	"github.com/levtul/tmp/model"
 	"github.com/levtul/tmp/walker"
-	"log"
 	"strings"
+	"unicode/utf8"
 )
 
-func Walk(sql string) (*walker.Walker, error) {
-	exprs := model.SplitterReg.Split(sql, -1)
+var walkedStatements = []string{"CREATE SCHEMA", "CREATE TABLE", "CREATE INDEX", "CREATE UNIQUE INDEX", "ALTER TABLE", "COMMENT ON", "DROP TABLE", "DROP INDEX", "SET search_path", "SET SCHEMA"}
+
+// Walk builds the schema model from the sources. If the schema has errors,
+// Walk returns them together with the warnings as model.Diagnostics.
+func Walk(sources ...Source) (*walker.Walker, error) {
 	myWalker := walker.NewWalker()
 	w := &walk.AstWalker{}
-	for _, expr := range exprs {
-		if strings.HasPrefix(expr, "CREATE SCHEMA") ||
-			strings.HasPrefix(expr, "CREATE TABLE") ||
-			strings.HasPrefix(expr, "ALTER TABLE") {
-			if model.GeneratedReg.MatchString(expr) {
+	files := make([]string, 0, len(sources))
+	for _, source := range sources {
+		files = append(files, source.File)
+		for _, stmt := range model.SplitStatements(source.File, source.Text) {
+			if !isWalked(stmt) || model.GeneratedReg.MatchString(stmt.Text) {
+				continue
+			}
+			if stmt.HasPrefix("CREATE INDEX") || stmt.HasPrefix("CREATE UNIQUE INDEX") {
+				myWalker.CreateIndex(stmt)
 				continue
 			}
 
-			stmts, err := parser.Parse(expr)
+			stmts, err := parser.Parse(stmt.Text)
 			if err != nil {
-				return nil, fmt.Errorf("parser error: %w, expr: %s\n", err, expr)
+				myWalker.Errs = append(myWalker.Errs, syntaxDiagnostic(stmt, err))
+				continue
 			}
 
-			w.Fn = myWalker.GetWalkFunc(expr)
+			w.Fn = myWalker.GetWalkFunc(stmt)
 			_, err = w.Walk(stmts, nil)
 			if err != nil {
-				return nil, fmt.Errorf("walker error: %w, expr: %s\n", err, expr)
+				myWalker.Errs = append(myWalker.Errs, stmt.Diagnostic(model.SeverityError, model.CodeSyntax, 0, fmt.Sprintf("walker error: %s", err)))
 			}
 		}
 	}
 
+	myWalker.ResolveReferences()
+
+	model.Diagnostics(myWalker.Warnings).Sort(files)
 	if len(myWalker.Errs) > 0 {
-		text := ""
-		for _, err := range myWalker.Errs {
-			text += err.Error() + ", "
+		diagnostics := append(model.Diagnostics{}, myWalker.Errs...)
+		diagnostics = append(diagnostics, myWalker.Warnings...)
+		diagnostics.Sort(files)
+		return nil, diagnostics
+	}
+
+	return myWalker, nil
+}
+
+func isWalked(stmt model.Statement) bool {
+	for _, prefix := range walkedStatements {
+		if stmt.HasPrefix(prefix) {
+			return true
 		}
-		return nil, fmt.Errorf("errors: %s", text)
 	}
-	if len(myWalker.Warnings) > 0 {
-		for _, warning := range myWalker.Warnings {
-			log.Printf("warning: %s\n", warning)
+
+	return false
+}
+
+// syntaxDiagnostic points to the place of a parser error. The parser reports
+// it as the statement text up to the wrong line followed by a caret line.
+func syntaxDiagnostic(stmt model.Statement, err error) *model.Diagnostic {
+	offset := 0
+	for _, detail := range errors.GetAllDetails(err) {
+		if !strings.HasPrefix(detail, "source SQL:\n") {
+			continue
+		}
+
+		lines := strings.Split(strings.TrimPrefix(detail, "source SQL:\n"), "\n")
+		caret := strings.IndexByte(lines[len(lines)-1], '^')
+		if len(lines) < 2 || caret < 0 {
+			break
+		}
+
+		stmtLines := strings.SplitAfter(stmt.Text, "\n")
+		for i := 0; i < len(lines)-2 && i < len(stmtLines); i++ {
+			offset += len(stmtLines[i])
+		}
+		rest := stmt.Text[offset:]
+		for ; caret > 0 && rest != ""; caret-- {
+			_, size := utf8.DecodeRuneInString(rest)
+			offset += size
+			rest = rest[size:]
 		}
+		break
 	}
 
-	return myWalker, nil
+	message := err.Error()
+	if i := strings.IndexByte(message, '\n'); i >= 0 {
+		message = message[:i]
+	}
+
+	return stmt.Diagnostic(model.SeverityError, model.CodeSyntax, offset, message)
 }
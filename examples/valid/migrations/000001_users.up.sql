@@ -0,0 +1,5 @@
+CREATE TABLE users
+( -- count:20
+    id   INT PRIMARY KEY,
+    name TEXT -- type:full_name
+);
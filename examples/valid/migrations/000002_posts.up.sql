@@ -0,0 +1,9 @@
+CREATE TABLE posts
+( -- count:50
+    id        INT PRIMARY KEY,
+    author_id INT REFERENCES users,
+    title     TEXT -- type:sentence
+);
+
+ALTER TABLE users ADD COLUMN email TEXT;
+COMMENT ON COLUMN users.email IS 'type:email unique';
@@ -0,0 +1,2 @@
+ALTER TABLE articles RENAME TO posts;
+ALTER TABLE users RENAME COLUMN full_name TO name;
@@ -0,0 +1,322 @@
+package model
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Directive is one generation setting from an annotation comment, either
+// "name:value" or a bare flag like "unique".
+//
+// An annotation is a comment that starts with a known directive and
+// consists of directives only, e.g. "-- range:[1 - 9] null:0.1 unique".
+// Values with spaces are written in brackets or quotes; the value of
+// "rule" runs to the end of the comment unless it is bracketed.
+type Directive struct {
+	Name  string
+	Value string
+	// Offset is the byte offset of the directive in the statement
+	Offset int
+}
+
+func (d Directive) String() string {
+	if d.Value == "" {
+		return d.Name
+	}
+
+	return d.Name + ":" + d.Value
+}
+
+const (
+	DirectiveCount   = "count"
+	DirectiveRule    = "rule"
+	DirectiveDensity = "density"
+	DirectiveFanOut  = "fanout"
+	DirectiveNull    = "null"
+	DirectiveSkew    = "skew"
+	DirectiveUnique  = "unique"
+)
+
+func isTableDirective(name string) bool {
+	return name == DirectiveCount || name == DirectiveRule || name == DirectiveDensity || name == DirectiveFanOut
+}
+
+func isColumnDirective(name string) bool {
+	if name == DirectiveNull || name == DirectiveSkew || name == DirectiveUnique {
+		return true
+	}
+	_, ok := generationTypes[name]
+	return ok
+}
+
+func isFlagDirective(name string) bool {
+	return name == DirectiveUnique
+}
+
+// ParseAnnotation parses directives from the comment text. Comments that
+// are not annotations (do not start with a known "name:value" directive and
+// are not made of flags only) are regular comments, for them ParseAnnotation
+// returns no directives and no error.
+func ParseAnnotation(comment string) ([]Directive, error) {
+	return ParseComment(Comment{Text: comment})
+}
+
+// ParseComment parses directives like ParseAnnotation, offsets of directives
+// and errors are counted from the start of the statement the comment is in.
+func ParseComment(comment Comment) ([]Directive, error) {
+	trimmed := strings.TrimLeftFunc(comment.Text, unicode.IsSpace)
+	lead := comment.Offset + len(comment.Text) - len(trimmed)
+	s := []rune(strings.TrimRightFunc(trimmed, unicode.IsSpace))
+	offset := func(i int) int {
+		return lead + len(string(s[:i]))
+	}
+
+	var res []Directive
+	strict := false
+	for i := 0; i < len(s); {
+		if unicode.IsSpace(s[i]) {
+			i++
+			continue
+		}
+
+		start := i
+		for i < len(s) && (unicode.IsLetter(s[i]) || unicode.IsDigit(s[i]) || s[i] == '_') {
+			i++
+		}
+		d := Directive{Name: string(s[start:i]), Offset: offset(start)}
+		known := isTableDirective(d.Name) || isColumnDirective(d.Name)
+
+		if i < len(s) && s[i] == ':' && d.Name != "" {
+			i++
+			var value []rune
+			value, i = directiveValue(s, i, d.Name == DirectiveRule)
+			d.Value = strings.TrimSpace(string(value))
+		} else if !isFlagDirective(d.Name) {
+			known = false
+		}
+
+		if !known || (i < len(s) && !unicode.IsSpace(s[i])) {
+			if !strict {
+				return nil, nil
+			}
+			end := i
+			for end < len(s) && !unicode.IsSpace(s[end]) {
+				end++
+			}
+			return nil, &SourceError{
+				Offset: offset(start),
+				Err:    fmt.Errorf("invalid directive %q in annotation %q", string(s[start:end]), string(s)),
+			}
+		}
+
+		strict = strict || !isFlagDirective(d.Name)
+		res = append(res, d)
+	}
+
+	return res, nil
+}
+
+// directiveValue reads a value starting at s[i]: a bracketed value keeps its
+// brackets, a quoted value loses its quotes, otherwise the value lasts until
+// a space (or until the end of the comment if rest is set).
+func directiveValue(s []rune, i int, rest bool) ([]rune, int) {
+	if i >= len(s) {
+		return nil, i
+	}
+
+	switch s[i] {
+	case '[':
+		depth, start := 0, i
+		for ; i < len(s); i++ {
+			if s[i] == '[' {
+				depth++
+			} else if s[i] == ']' {
+				depth--
+				if depth == 0 {
+					if rest {
+						return s[start+1 : i], i + 1
+					}
+					return s[start : i+1], i + 1
+				}
+			}
+		}
+		return s[start:], i
+	case '"', '\'':
+		quote, start := s[i], i+1
+		for i = start; i < len(s) && s[i] != quote; i++ {
+		}
+		if i < len(s) {
+			return s[start:i], i + 1
+		}
+		return s[start:], i
+	}
+
+	start := i
+	if rest {
+		return s[start:], len(s)
+	}
+	for i < len(s) && !unicode.IsSpace(s[i]) {
+		i++
+	}
+
+	return s[start:i], i
+}
+
+// TableAnnotations holds directives collected from the comments of
+// a CREATE TABLE statement.
+type TableAnnotations struct {
+	Table   []Directive
+	Columns map[string][]Directive
+}
+
+func ParseTableAnnotations(stmt string) (*TableAnnotations, error) {
+	res := &TableAnnotations{Columns: map[string][]Directive{}}
+	elements, dangling := ScanCreateTable(stmt)
+
+	for _, comment := range dangling {
+		ds, err := ParseComment(comment)
+		if err != nil {
+			return nil, err
+		}
+		for _, d := range ds {
+			if !isTableDirective(d.Name) {
+				return nil, &SourceError{Offset: d.Offset, Err: fmt.Errorf("directive %s is not attached to a column", d)}
+			}
+			res.Table = append(res.Table, d)
+		}
+	}
+
+	for _, element := range elements {
+		for _, comment := range element.Comments {
+			ds, err := ParseComment(comment)
+			if err != nil {
+				return nil, err
+			}
+			for _, d := range ds {
+				switch {
+				case isTableDirective(d.Name):
+					res.Table = append(res.Table, d)
+				case element.Column == "":
+					return nil, &SourceError{Offset: d.Offset, Err: fmt.Errorf("directive %s is not attached to a column: %s", d, element.Text)}
+				default:
+					res.Columns[element.Column] = append(res.Columns[element.Column], d)
+				}
+			}
+		}
+	}
+
+	return res, nil
+}
+
+// ApplyColumnDirectives sets generation settings of the column.
+func ApplyColumnDirectives(table *Table, column *Column, ds []Directive) error {
+	generator := ""
+	for _, d := range ds {
+		if err := applyColumnDirective(table, column, d, &generator); err != nil {
+			return &SourceError{Offset: d.Offset, Err: err}
+		}
+	}
+
+	return nil
+}
+
+func applyColumnDirective(table *Table, column *Column, d Directive, generator *string) error {
+	switch d.Name {
+	case DirectiveNull:
+		ratio, err := strconv.ParseFloat(d.Value, 64)
+		if err != nil || ratio < 0 || ratio > 1 {
+			return fmt.Errorf("column %s: invalid null ratio: %s, expected value from 0 to 1", column.Name, d.Value)
+		}
+		if ratio > 0 && !column.Nullable(table) {
+			return fmt.Errorf("column %s: %s is not allowed, column is not nullable", column.Name, d)
+		}
+		column.NullRatio = ratio
+	case DirectiveSkew:
+		skew, err := strconv.ParseFloat(d.Value, 64)
+		if err != nil {
+			return fmt.Errorf("column %s: invalid skew: %s", column.Name, d.Value)
+		}
+		if err := CheckSkew(skew); err != nil {
+			return fmt.Errorf("column %s: %w", column.Name, err)
+		}
+		column.Skew = skew
+	case DirectiveUnique:
+		if !column.Unique {
+			column.Unique = true
+			table.UniqueConstraints = append(table.UniqueConstraints, &UniqueConstraint{
+				Name:    ConstraintName(table.Name, []string{column.Name}, "key"),
+				Columns: []string{column.Name},
+			})
+		}
+	case DirectiveCount, DirectiveRule, DirectiveDensity, DirectiveFanOut:
+		return fmt.Errorf("column %s: %s is a table directive", column.Name, d)
+	default:
+		if *generator != "" {
+			return fmt.Errorf("column %s: several generators: %s and %s", column.Name, *generator, d)
+		}
+		*generator = d.String()
+
+		gt, err := NewGenerationTypeFromString(*generator, column.Type)
+		if err != nil {
+			return fmt.Errorf("column %s: %w", column.Name, err)
+		}
+		column.GenerationType = *gt
+	}
+
+	return nil
+}
+
+// ApplyTableDirectives sets generation settings of the table. It must be
+// called after all columns of the table are known.
+func ApplyTableDirectives(table *Table, ds []Directive) error {
+	if table.TableGenerationSettings == nil {
+		table.TableGenerationSettings = &TableGenerationSettings{RowsCount: DefaultRowsCount}
+	}
+
+	for _, d := range ds {
+		if err := applyTableDirective(table, d); err != nil {
+			return &SourceError{Offset: d.Offset, Err: err}
+		}
+	}
+
+	return nil
+}
+
+func applyTableDirective(table *Table, d Directive) error {
+	settings := table.TableGenerationSettings
+	switch d.Name {
+	case DirectiveCount:
+		count, err := strconv.Atoi(d.Value)
+		if err != nil || count < 0 {
+			return fmt.Errorf("invalid rows count: %s", d.Value)
+		}
+		settings.RowsCount = count
+	case DirectiveRule:
+		rule, err := NewRowRuleFromString(d.Value)
+		if err != nil {
+			return err
+		}
+		if err := rule.ValidateTable(table); err != nil {
+			return err
+		}
+		settings.Rules = append(settings.Rules, rule)
+	case DirectiveDensity:
+		density, err := ParseDensity(d.Value)
+		if err != nil {
+			return err
+		}
+		settings.Density = density
+	case DirectiveFanOut:
+		fanOut, err := ParseFanOut(d.Value)
+		if err != nil {
+			return err
+		}
+		settings.FanOut = fanOut
+	default:
+		return fmt.Errorf("%s is a column directive", d)
+	}
+
+	return nil
+}
@@ -0,0 +1,41 @@
+package model
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseAnnotation(t *testing.T) {
+	tests := []struct {
+		comment string
+		want    []string
+		err     bool
+	}{
+		{comment: "just a comment", want: nil},
+		{comment: "count:50", want: []string{"count:50"}},
+		{comment: "  range:[1 - 100] null:0.1  ", want: []string{"range:[1 - 100]", "null:0.1"}},
+		{comment: "unique", want: []string{"unique"}},
+		{comment: "type:sentence unique", want: []string{"type:sentence", "unique"}},
+		{comment: "unique values only", want: nil},
+		{comment: "rule:max_price >= min_price", want: []string{"rule:max_price >= min_price"}},
+		{comment: "rule:[a > b] count:5", want: []string{"rule:a > b", "count:5"}},
+		{comment: `oneof:"a b"`, want: []string{"oneof:a b"}},
+		{comment: "count:5 something", err: true},
+		{comment: "null:0.1 unknown:1", err: true},
+	}
+
+	for _, tt := range tests {
+		directives, err := ParseAnnotation(tt.comment)
+		if (err != nil) != tt.err {
+			t.Errorf("ParseAnnotation(%q): error %v, expected error %t", tt.comment, err, tt.err)
+			continue
+		}
+		var got []string
+		for _, d := range directives {
+			got = append(got, d.String())
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseAnnotation(%q) = %q, expected %q", tt.comment, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package model
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+type Severity = string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+)
+
+// Diagnostic codes.
+const (
+	CodeSyntax      = "syntax"
+	CodeDuplicate   = "duplicate"
+	CodeNotFound    = "not-found"
+	CodeAnnotation  = "annotation"
+	CodeForeignKey  = "foreign-key"
+	CodeUnsupported = "unsupported"
+)
+
+// Diagnostic is an error or a warning about the schema, pointing to
+// the place in the original source it refers to.
+type Diagnostic struct {
+	File     string   `json:"file"`
+	Line     int      `json:"line"`
+	Column   int      `json:"column"`
+	Severity Severity `json:"severity"`
+	Code     string   `json:"code"`
+	Message  string   `json:"message"`
+}
+
+// Error formats the diagnostic compiler-style: "file:line:column: severity[code]: message".
+func (d *Diagnostic) Error() string {
+	return fmt.Sprintf("%s:%d:%d: %s[%s]: %s", d.File, d.Line, d.Column, d.Severity, d.Code, d.Message)
+}
+
+// SourceError is an error found at the byte offset of a statement.
+type SourceError struct {
+	Offset int
+	Err    error
+}
+
+func (e *SourceError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *SourceError) Unwrap() error {
+	return e.Err
+}
+
+// Diagnostics is returned as an error when the schema has errors.
+type Diagnostics []*Diagnostic
+
+func (ds Diagnostics) Error() string {
+	lines := make([]string, 0, len(ds))
+	for _, d := range ds {
+		lines = append(lines, d.Error())
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// Sort orders diagnostics by their position in the sources.
+func (ds Diagnostics) Sort(files []string) {
+	fileIdx := make(map[string]int, len(files))
+	for i, file := range files {
+		fileIdx[file] = i
+	}
+
+	sort.SliceStable(ds, func(i, j int) bool {
+		if ds[i].File != ds[j].File {
+			return fileIdx[ds[i].File] < fileIdx[ds[j].File]
+		}
+		if ds[i].Line != ds[j].Line {
+			return ds[i].Line < ds[j].Line
+		}
+		return ds[i].Column < ds[j].Column
+	})
+}
@@ -3,85 +3,18 @@ package model
 import (
 	"fmt"
 	"github.com/auxten/postgresql-parser/pkg/sql/types"
-	"github.com/go-faker/faker/v4"
 	"math/rand"
-	"reflect"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
 )
 
-type GenerationPreset = int
-
-const (
-	GenerationPresetName GenerationPreset = iota
-	GenerationPresetSurname
-	GenerationPresetPatronymic
-	GenerationPresetNameRu
-	GenerationPresetSurnameRu
-	GenerationPresetPatronymicRu
-	GenerationPresetAddress
-	GenerationPresetAddressRu
-	GenerationPresetPhone
-	GenerationPresetEmail
-)
-
-func String(p GenerationPreset) string {
-	switch p {
-	case GenerationPresetName:
-		return "name"
-	case GenerationPresetSurname:
-		return "surname"
-	case GenerationPresetPatronymic:
-		return "patronymic"
-	case GenerationPresetNameRu:
-		return "name_ru"
-	case GenerationPresetSurnameRu:
-		return "surname_ru"
-	case GenerationPresetPatronymicRu:
-		return "patronymic_ru"
-	case GenerationPresetAddress:
-		return "address"
-	case GenerationPresetAddressRu:
-		return "address_ru"
-	case GenerationPresetPhone:
-		return "phone"
-	case GenerationPresetEmail:
-		return "email"
-	}
-
-	return ""
-}
-
-func GenerationPresetFromString(s string) (GenerationPreset, error) {
-	switch s {
-	case "name":
-		return GenerationPresetName, nil
-	case "surname":
-		return GenerationPresetSurname, nil
-	case "patronymic":
-		return GenerationPresetPatronymic, nil
-	case "name_ru":
-		return GenerationPresetNameRu, nil
-	case "surname_ru":
-		return GenerationPresetSurnameRu, nil
-	case "patronymic_ru":
-		return GenerationPresetPatronymicRu, nil
-	case "address":
-		return GenerationPresetAddress, nil
-	case "address_ru":
-		return GenerationPresetAddressRu, nil
-	case "phone":
-		return GenerationPresetPhone, nil
-	case "email":
-		return GenerationPresetEmail, nil
-	}
-
-	return 0, fmt.Errorf("unknown generation preset: %s", s)
-}
-
 type GenerationTypeOneof struct {
 	Values []interface{}
+	// Type is the column type, values equal in it are counted once
+	Type     *types.T
+	distinct []interface{}
 }
 
 type GenerationTypeRange struct {
@@ -91,20 +24,48 @@ type GenerationTypeRange struct {
 }
 
 type GenerationTypePreset struct {
-	Preset GenerationPreset
+	Preset *GenerationPreset
 }
 
+// GenerationType generates values of a column annotated with
+// "<CommentString()>:<value>". SetValue receives the text after the colon,
+// ValidateType checks that the generator suits the column type.
 type GenerationType interface {
-	generationType()
 	CommentString() string
 	ValidateType(t *types.T) error
 	SetValue(v string) error
-	GenerateValue() interface{}
+	GenerateValue(row *RowContext) interface{}
 }
 
-func (*GenerationTypeOneof) generationType()  {}
-func (*GenerationTypeRange) generationType()  {}
-func (*GenerationTypePreset) generationType() {}
+// GenerationTypeFactory creates an empty generator for a column of type t.
+type GenerationTypeFactory func(t *types.T) GenerationType
+
+var generationTypes = map[string]GenerationTypeFactory{}
+
+// RegisterGenerationType makes a generator available in column comments
+// as "<name>:<value>". Registering an existing name replaces the previous
+// generator, including the built-in ones. It is meant to be called from
+// init functions, before any schema is walked.
+func RegisterGenerationType(name string, factory GenerationTypeFactory) {
+	generationTypes[name] = factory
+}
+
+// GenerationTypes returns names of all registered generators.
+func GenerationTypes() []string {
+	res := make([]string, 0, len(generationTypes))
+	for name := range generationTypes {
+		res = append(res, name)
+	}
+	sort.Strings(res)
+
+	return res
+}
+
+func init() {
+	RegisterGenerationType("oneof", func(t *types.T) GenerationType { return &GenerationTypeOneof{Type: t} })
+	RegisterGenerationType("range", func(t *types.T) GenerationType { return &GenerationTypeRange{Type: t} })
+	RegisterGenerationType("type", func(*types.T) GenerationType { return &GenerationTypePreset{} })
+}
 
 func (*GenerationTypeOneof) CommentString() string {
 	return "oneof"
@@ -130,6 +91,7 @@ func (gto *GenerationTypeOneof) SetValue(v string) error {
 		return fmt.Errorf("invalid oneof value: %s", v)
 	}
 
+	gto.distinct = nil
 	gto.Values = make([]interface{}, len(arr))
 	for i, v := range arr {
 		gto.Values[i] = v
@@ -240,23 +202,20 @@ func (gtr *GenerationTypeRange) ValidateType(t *types.T) error {
 	}
 }
 func (gtp *GenerationTypePreset) ValidateType(t *types.T) error {
-	switch gtp.Preset {
-	case GenerationPresetName, GenerationPresetSurname, GenerationPresetPatronymic, GenerationPresetNameRu, GenerationPresetSurnameRu, GenerationPresetPatronymicRu, GenerationPresetEmail, GenerationPresetAddress, GenerationPresetAddressRu, GenerationPresetPhone:
-		if t.Family() != types.StringFamily {
-			return fmt.Errorf("generation type %s can be used only with string type, got %s", String(gtp.Preset), t.String())
+	for _, f := range gtp.Preset.Families {
+		if t.Family() == f {
+			return nil
 		}
-	default:
-		return fmt.Errorf("unknown generation preset: %s", String(gtp.Preset))
 	}
 
-	return nil
+	return fmt.Errorf("generation type %s cannot be used with type %s", gtp.Preset.String(), t.String())
 }
 
-func (gto *GenerationTypeOneof) GenerateValue() interface{} {
+func (gto *GenerationTypeOneof) GenerateValue(*RowContext) interface{} {
 	return gto.Values[rand.Intn(len(gto.Values))]
 }
 
-func (gtr *GenerationTypeRange) GenerateValue() interface{} {
+func (gtr *GenerationTypeRange) GenerateValue(*RowContext) interface{} {
 	switch gtr.Type.Family() {
 	case types.IntFamily:
 		fromInt := gtr.From.(int)
@@ -283,53 +242,71 @@ func (gtr *GenerationTypeRange) GenerateValue() interface{} {
 	}
 }
 
-func (gtp *GenerationTypePreset) GenerateValue() interface{} {
-	switch gtp.Preset {
-	case GenerationPresetName:
-		return faker.FirstName()
-	case GenerationPresetSurname:
-		return faker.LastName()
-	case GenerationPresetPatronymic:
-		return faker.FirstNameMale()
-	case GenerationPresetNameRu:
-		s, _ := faker.GetPerson().RussianFirstNameMale(reflect.Value{})
-		return s
-	case GenerationPresetSurnameRu:
-		s, _ := faker.GetPerson().RussianLastNameMale(reflect.Value{})
-		return s
-	case GenerationPresetPatronymicRu:
-		s, _ := faker.GetPerson().RussianFirstNameMale(reflect.Value{})
-		return s
-	case GenerationPresetAddress:
-		addr, _ := faker.GetAddress().RealWorld(reflect.Value{})
-		a := addr.(faker.RealAddress)
-		return a.Address
-	case GenerationPresetAddressRu:
-		addr, _ := faker.GetAddress().RealWorld(reflect.Value{})
-		a := addr.(faker.RealAddress)
-		return a.Address
-	case GenerationPresetPhone:
-		return "+7 " + faker.Phonenumber()
-	case GenerationPresetEmail:
-		return faker.Email()
+func (gto *GenerationTypeOneof) DomainSize() int64 {
+	return int64(len(gto.distinctValues()))
+}
+
+func (gto *GenerationTypeOneof) ValueAt(i int64) interface{} {
+	return gto.distinctValues()[i]
+}
+
+// distinctValues returns the values without repeats, values PostgreSQL
+// stores as equal are repeats.
+func (gto *GenerationTypeOneof) distinctValues() []interface{} {
+	if gto.distinct != nil {
+		return gto.distinct
 	}
 
-	return nil
+	seen := map[string]bool{}
+	gto.distinct = make([]interface{}, 0, len(gto.Values))
+	for _, v := range gto.Values {
+		key := NormalizeValue(v, gto.Type)
+		if !seen[key] {
+			seen[key] = true
+			gto.distinct = append(gto.distinct, v)
+		}
+	}
+
+	return gto.distinct
+}
+
+// DomainSize counts integers, days of dates or seconds of time values in the
+// range, float ranges are not enumerable.
+func (gtr *GenerationTypeRange) DomainSize() int64 {
+	switch gtr.Type.Family() {
+	case types.IntFamily:
+		return int64(gtr.To.(int) - gtr.From.(int))
+	case types.DateFamily:
+		return int64(gtr.To.(time.Time).Sub(gtr.From.(time.Time)) / (24 * time.Hour))
+	case types.TimeFamily, types.TimestampFamily:
+		return gtr.To.(time.Time).Unix() - gtr.From.(time.Time).Unix()
+	default:
+		return -1
+	}
 }
 
-func generationTypeFromString(s string, t *types.T) (res GenerationType, err error) {
-	switch s {
-	case "oneof":
-		res = &GenerationTypeOneof{}
-	case "range":
-		res = &GenerationTypeRange{Type: t}
-	case "type":
-		res = &GenerationTypePreset{}
+func (gtr *GenerationTypeRange) ValueAt(i int64) interface{} {
+	switch gtr.Type.Family() {
+	case types.IntFamily:
+		return gtr.From.(int) + int(i)
+	case types.DateFamily:
+		return gtr.From.(time.Time).AddDate(0, 0, int(i))
 	default:
-		err = fmt.Errorf("unknown generation type: %s", s)
+		return time.Unix(gtr.From.(time.Time).Unix()+i, 0)
+	}
+}
+
+func (gtp *GenerationTypePreset) GenerateValue(row *RowContext) interface{} {
+	return gtp.Preset.Generate(row)
+}
+
+func generationTypeFromString(s string, t *types.T) (GenerationType, error) {
+	factory, ok := generationTypes[s]
+	if !ok {
+		return nil, fmt.Errorf("unknown generation type: %s", s)
 	}
 
-	return
+	return factory(t), nil
 }
 
 func NewGenerationTypeFromString(s string, t *types.T) (*GenerationType, error) {
@@ -358,6 +335,68 @@ func NewGenerationTypeFromString(s string, t *types.T) (*GenerationType, error)
 	return &gt, nil
 }
 
+const DefaultRowsCount = 100
+
 type TableGenerationSettings struct {
 	RowsCount int
+	Rules     []*RowRule
+
+	// Density is the share of all combinations of referenced rows to
+	// generate for a junction table, it overrides RowsCount if set.
+	Density float64
+	// FanOut is the number of rows per row of the first referenced table of
+	// a junction table, it overrides RowsCount if set.
+	FanOut *FanOut
+}
+
+// FanOut is an inclusive range of rows per referenced row.
+type FanOut struct {
+	Min int
+	Max int
+}
+
+// ParseFanOut parses a fan-out written as "3" or "[1 - 5]".
+func ParseFanOut(s string) (*FanOut, error) {
+	if n, err := strconv.Atoi(s); err == nil && n >= 0 {
+		return &FanOut{Min: n, Max: n}, nil
+	}
+
+	if len(s) < 2 || s[0] != '[' || s[len(s)-1] != ']' {
+		return nil, fmt.Errorf("invalid fanout value: %s", s)
+	}
+	arr := strings.Split(s[1:len(s)-1], " - ")
+	if len(arr) != 2 {
+		return nil, fmt.Errorf("invalid fanout value: %s", s)
+	}
+	from, err := strconv.Atoi(arr[0])
+	if err != nil {
+		return nil, fmt.Errorf("invalid fanout value: %s, cannot parse int: %w", s, err)
+	}
+	to, err := strconv.Atoi(arr[1])
+	if err != nil {
+		return nil, fmt.Errorf("invalid fanout value: %s, cannot parse int: %w", s, err)
+	}
+	if from < 0 || to < from {
+		return nil, fmt.Errorf("invalid fanout value: %s", s)
+	}
+
+	return &FanOut{Min: from, Max: to}, nil
+}
+
+// ParseDensity parses a share of combinations from 0 to 1.
+func ParseDensity(s string) (float64, error) {
+	density, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid density: %s", s)
+	}
+
+	return density, CheckDensity(density)
+}
+
+func CheckDensity(density float64) error {
+	if density <= 0 || density > 1 {
+		return fmt.Errorf("invalid density: %v, expected value greater than 0 and not greater than 1", density)
+	}
+
+	return nil
 }
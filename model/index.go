@@ -0,0 +1,591 @@
+package model
+
+import (
+	"fmt"
+	"github.com/auxten/postgresql-parser/pkg/sql/parser"
+	"github.com/auxten/postgresql-parser/pkg/sql/sem/tree"
+	"github.com/auxten/postgresql-parser/pkg/sql/types"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// IndexDef is a CREATE INDEX statement. The SQL parser does not support
+// expression and partial indexes, so the statement is scanned by
+// ParseCreateIndex and expressions are parsed separately.
+type IndexDef struct {
+	Name        string
+	Schema      string
+	Table       string
+	Unique      bool
+	IfNotExists bool
+	// NullsNotDistinct makes NULL keys conflict with each other
+	NullsNotDistinct bool
+	Elements         []IndexElement
+	Where            string
+	WhereOffset      int
+}
+
+// IndexElement is either a column or an expression, Offset is its byte offset
+// in the statement.
+type IndexElement struct {
+	Column string
+	Expr   string
+	Offset int
+}
+
+type indexToken struct {
+	text   string
+	word   bool
+	offset int
+	end    int
+}
+
+// indexTokenizer reads words, quoted identifiers, strings, parenthesized
+// groups and punctuation, skipping spaces and comments.
+type indexTokenizer struct {
+	s   string
+	pos int
+}
+
+func (t *indexTokenizer) next() (indexToken, bool) {
+	for t.pos < len(t.s) {
+		switch {
+		case unicode.IsSpace(rune(t.s[t.pos])):
+			t.pos++
+		case strings.HasPrefix(t.s[t.pos:], "--"):
+			end := strings.IndexByte(t.s[t.pos:], '\n')
+			if end < 0 {
+				end = len(t.s) - t.pos
+			}
+			t.pos += end
+		case strings.HasPrefix(t.s[t.pos:], "/*"):
+			t.pos = blockCommentEnd(t.s, t.pos)
+		default:
+			return t.token(), true
+		}
+	}
+
+	return indexToken{offset: t.pos, end: t.pos}, false
+}
+
+func (t *indexTokenizer) token() indexToken {
+	start := t.pos
+	switch c := t.s[t.pos]; {
+	case c == '"':
+		t.pos = quoteEnd(t.s, t.pos)
+		text := strings.ReplaceAll(strings.Trim(t.s[start:t.pos], `"`), `""`, `"`)
+		return indexToken{text: text, word: true, offset: start, end: t.pos}
+	case c == '\'':
+		t.pos = quoteEnd(t.s, t.pos)
+	case c == '(':
+		t.pos = groupEnd(t.s, t.pos)
+	case isIdentRune(firstRune(t.s[t.pos:])):
+		for t.pos < len(t.s) && isIdentRune(firstRune(t.s[t.pos:])) {
+			_, size := utf8.DecodeRuneInString(t.s[t.pos:])
+			t.pos += size
+		}
+		return indexToken{text: strings.ToLower(t.s[start:t.pos]), word: true, offset: start, end: t.pos}
+	default:
+		t.pos++
+	}
+
+	return indexToken{text: t.s[start:t.pos], offset: start, end: t.pos}
+}
+
+func firstRune(s string) rune {
+	r, _ := utf8.DecodeRuneInString(s)
+	return r
+}
+
+// groupEnd returns the position after the parenthesis matching s[i].
+func groupEnd(s string, i int) int {
+	depth := 0
+	for i < len(s) {
+		switch {
+		case s[i] == '\'' || s[i] == '"':
+			i = quoteEnd(s, i)
+			continue
+		case strings.HasPrefix(s[i:], "--"):
+			end := strings.IndexByte(s[i:], '\n')
+			if end < 0 {
+				return len(s)
+			}
+			i += end
+			continue
+		case strings.HasPrefix(s[i:], "/*"):
+			i = blockCommentEnd(s, i)
+			continue
+		case s[i] == '(':
+			depth++
+		case s[i] == ')':
+			depth--
+			if depth == 0 {
+				return i + 1
+			}
+		}
+		i++
+	}
+
+	return len(s)
+}
+
+// ParseCreateIndex scans CREATE [UNIQUE] INDEX [CONCURRENTLY] [[IF NOT EXISTS] name]
+// ON [ONLY] table [USING method] (elements) [INCLUDE (...)] [NULLS [NOT] DISTINCT]
+// [WITH (...)] [TABLESPACE name] [WHERE predicate].
+func ParseCreateIndex(stmt string) (*IndexDef, error) {
+	t := &indexTokenizer{s: stmt}
+	res := &IndexDef{}
+
+	tok, _ := t.next()
+	expect := func(words ...string) error {
+		for _, word := range words {
+			if tok.text != word {
+				return &SourceError{Offset: tok.offset, Err: fmt.Errorf("expected %s in CREATE INDEX", strings.ToUpper(word))}
+			}
+			tok, _ = t.next()
+		}
+		return nil
+	}
+
+	if err := expect("create"); err != nil {
+		return nil, err
+	}
+	if tok.text == "unique" {
+		res.Unique = true
+		tok, _ = t.next()
+	}
+	if err := expect("index"); err != nil {
+		return nil, err
+	}
+	if tok.text == "concurrently" {
+		tok, _ = t.next()
+	}
+	if tok.text == "if" {
+		tok, _ = t.next()
+		if err := expect("not", "exists"); err != nil {
+			return nil, err
+		}
+		res.IfNotExists = true
+	}
+	if tok.word && tok.text != "on" {
+		res.Name = tok.text
+		tok, _ = t.next()
+	}
+	if err := expect("on"); err != nil {
+		return nil, err
+	}
+	if tok.text == "only" {
+		tok, _ = t.next()
+	}
+	if !tok.word {
+		return nil, &SourceError{Offset: tok.offset, Err: fmt.Errorf("table name expected in CREATE INDEX")}
+	}
+	res.Table = tok.text
+	tok, _ = t.next()
+	if tok.text == "." {
+		tok, _ = t.next()
+		res.Schema, res.Table = res.Table, tok.text
+		tok, _ = t.next()
+	}
+	if tok.text == "using" {
+		t.next()
+		tok, _ = t.next()
+	}
+	if !strings.HasPrefix(tok.text, "(") {
+		return nil, &SourceError{Offset: tok.offset, Err: fmt.Errorf("index elements expected in CREATE INDEX")}
+	}
+	res.Elements = indexElements(stmt, tok.offset+1, tok.end-1)
+
+	for {
+		tok, ok := t.next()
+		if !ok || tok.text == ";" {
+			break
+		}
+		switch tok.text {
+		case "where":
+			res.Where = strings.TrimRight(strings.TrimSpace(stmt[tok.end:]), ";")
+			res.WhereOffset = tok.end + len(stmt[tok.end:]) - len(strings.TrimLeftFunc(stmt[tok.end:], unicode.IsSpace))
+			return res, nil
+		case "nulls":
+			next, _ := t.next()
+			if next.text == "not" {
+				next, _ = t.next()
+				res.NullsNotDistinct = true
+			}
+			if next.text != "distinct" {
+				return nil, &SourceError{Offset: next.offset, Err: fmt.Errorf("expected DISTINCT in CREATE INDEX")}
+			}
+		case "include", "with", "tablespace":
+		default:
+			if !tok.word && !strings.HasPrefix(tok.text, "(") {
+				return nil, &SourceError{Offset: tok.offset, Err: fmt.Errorf("unexpected %q in CREATE INDEX", tok.text)}
+			}
+		}
+	}
+
+	return res, nil
+}
+
+// indexElements splits the element list s[from:to] by top level commas.
+func indexElements(s string, from, to int) []IndexElement {
+	var res []IndexElement
+	t := &indexTokenizer{s: s[:to], pos: from}
+	for {
+		tok, ok := t.next()
+		if !ok {
+			break
+		}
+
+		element := IndexElement{Offset: tok.offset}
+		switch {
+		case strings.HasPrefix(tok.text, "("):
+			element.Expr = s[tok.offset+1 : tok.end-1]
+		case tok.word:
+			element.Column = tok.text
+			save := t.pos
+			if next, ok := t.next(); ok && strings.HasPrefix(next.text, "(") {
+				element.Column, element.Expr = "", s[tok.offset:next.end]
+			} else {
+				t.pos = save
+			}
+		}
+		res = append(res, element)
+
+		// skip COLLATE, operator class, ASC/DESC and NULLS FIRST/LAST
+		for {
+			tok, ok := t.next()
+			if !ok || tok.text == "," {
+				break
+			}
+		}
+	}
+
+	return res
+}
+
+var indexFunctions = map[string]func(v interface{}) interface{}{
+	"lower": stringFunc(strings.ToLower),
+	"upper": stringFunc(strings.ToUpper),
+	"trim":  stringFunc(strings.TrimSpace),
+	"btrim": stringFunc(strings.TrimSpace),
+	"ltrim": stringFunc(func(s string) string { return strings.TrimLeftFunc(s, unicode.IsSpace) }),
+	"rtrim": stringFunc(func(s string) string { return strings.TrimRightFunc(s, unicode.IsSpace) }),
+	"date":  toDate,
+}
+
+func stringFunc(f func(string) string) func(v interface{}) interface{} {
+	return func(v interface{}) interface{} {
+		if s, ok := v.(string); ok {
+			return f(s)
+		}
+		return nil
+	}
+}
+
+func toDate(v interface{}) interface{} {
+	if t, ok := v.(time.Time); ok {
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	}
+	return nil
+}
+
+// IndexExpr is a key expression of an expression index or the predicate of
+// a partial index, evaluated on generated rows. Only column references,
+// constants, comparisons, boolean operators, casts to date and text and
+// the functions lower, upper, trim, btrim, ltrim, rtrim and date are supported.
+type IndexExpr struct {
+	expr    tree.Expr
+	columns []*tree.UnresolvedName
+}
+
+func NewIndexExpr(s string) (*IndexExpr, error) {
+	expr, err := parser.ParseExpr(s)
+	if err != nil {
+		return nil, err
+	}
+
+	e := &IndexExpr{expr: expr}
+	if err := e.check(expr); err != nil {
+		return nil, fmt.Errorf("expression %s is not supported: %w", tree.AsString(expr), err)
+	}
+
+	return e, nil
+}
+
+// IndexColumn returns an expression referencing the column.
+func IndexColumn(name string) *IndexExpr {
+	column := &tree.UnresolvedName{NumParts: 1, Parts: tree.NameParts{name}}
+	return &IndexExpr{expr: column, columns: []*tree.UnresolvedName{column}}
+}
+
+func (e *IndexExpr) check(expr tree.Expr) error {
+	if expr == tree.DNull {
+		return nil
+	}
+
+	switch n := expr.(type) {
+	case *tree.UnresolvedName:
+		if n.Star {
+			return fmt.Errorf("* is not a column")
+		}
+		e.columns = append(e.columns, n)
+	case *tree.StrVal, *tree.NumVal, *tree.DBool:
+	case *tree.ParenExpr:
+		return e.check(n.Expr)
+	case *tree.NotExpr:
+		return e.check(n.Expr)
+	case *tree.AndExpr:
+		if err := e.check(n.Left); err != nil {
+			return err
+		}
+		return e.check(n.Right)
+	case *tree.OrExpr:
+		if err := e.check(n.Left); err != nil {
+			return err
+		}
+		return e.check(n.Right)
+	case *tree.ComparisonExpr:
+		switch n.Operator {
+		case tree.EQ, tree.NE, tree.LT, tree.LE, tree.GT, tree.GE, tree.IsDistinctFrom, tree.IsNotDistinctFrom:
+		default:
+			return fmt.Errorf("operator %s", n.Operator)
+		}
+		if err := e.check(n.Left); err != nil {
+			return err
+		}
+		return e.check(n.Right)
+	case *tree.CastExpr:
+		if f := n.Type.Family(); f != types.DateFamily && f != types.StringFamily {
+			return fmt.Errorf("cast to %s", n.Type.SQLStandardName())
+		}
+		return e.check(n.Expr)
+	case *tree.FuncExpr:
+		name := strings.ToLower(tree.AsString(&n.Func))
+		if _, ok := indexFunctions[name]; !ok || len(n.Exprs) != 1 {
+			return fmt.Errorf("function %s", name)
+		}
+		return e.check(n.Exprs[0])
+	default:
+		return fmt.Errorf("%s", tree.AsString(expr))
+	}
+
+	return nil
+}
+
+func (e *IndexExpr) String() string {
+	return tree.AsString(e.expr)
+}
+
+// Column returns the column name if the expression is a bare column.
+func (e *IndexExpr) Column() (string, bool) {
+	expr := e.expr
+	for {
+		paren, ok := expr.(*tree.ParenExpr)
+		if !ok {
+			break
+		}
+		expr = paren.Expr
+	}
+	if column, ok := expr.(*tree.UnresolvedName); ok {
+		return column.Parts[0], true
+	}
+
+	return "", false
+}
+
+// Columns returns names of the columns used by the expression.
+func (e *IndexExpr) Columns() []string {
+	res := make([]string, 0, len(e.columns))
+	for _, column := range e.columns {
+		res = append(res, column.Parts[0])
+	}
+
+	return res
+}
+
+func (e *IndexExpr) RenameColumn(name, newName string) {
+	for _, column := range e.columns {
+		if column.Parts[0] == name {
+			column.Parts[0] = newName
+		}
+	}
+}
+
+// Eval computes the expression for the row, nil is SQL NULL.
+func (e *IndexExpr) Eval(row map[string]interface{}) interface{} {
+	return evalIndexExpr(e.expr, row)
+}
+
+// Holds reports whether the predicate is true for the row.
+func (e *IndexExpr) Holds(row map[string]interface{}) bool {
+	b, ok := e.Eval(row).(bool)
+	return ok && b
+}
+
+func evalIndexExpr(expr tree.Expr, row map[string]interface{}) interface{} {
+	if expr == tree.DNull {
+		return nil
+	}
+
+	switch n := expr.(type) {
+	case *tree.UnresolvedName:
+		return row[n.Parts[0]]
+	case *tree.StrVal:
+		return n.RawString()
+	case *tree.NumVal:
+		f, err := strconv.ParseFloat(n.OrigString(), 64)
+		if err != nil {
+			return nil
+		}
+		return f
+	case *tree.DBool:
+		return bool(*n)
+	case *tree.ParenExpr:
+		return evalIndexExpr(n.Expr, row)
+	case *tree.NotExpr:
+		if b, ok := evalIndexExpr(n.Expr, row).(bool); ok {
+			return !b
+		}
+		return nil
+	case *tree.AndExpr:
+		l, lok := evalIndexExpr(n.Left, row).(bool)
+		r, rok := evalIndexExpr(n.Right, row).(bool)
+		if lok && !l || rok && !r {
+			return false
+		}
+		if lok && rok {
+			return true
+		}
+		return nil
+	case *tree.OrExpr:
+		l, lok := evalIndexExpr(n.Left, row).(bool)
+		r, rok := evalIndexExpr(n.Right, row).(bool)
+		if lok && l || rok && r {
+			return true
+		}
+		if lok && rok {
+			return false
+		}
+		return nil
+	case *tree.ComparisonExpr:
+		l, r := evalIndexExpr(n.Left, row), evalIndexExpr(n.Right, row)
+		switch n.Operator {
+		case tree.IsNotDistinctFrom, tree.IsDistinctFrom:
+			equal := l == nil && r == nil
+			if l != nil && r != nil {
+				c, ok := compareIndexValues(l, r)
+				equal = ok && c == 0
+			}
+			return equal == (n.Operator == tree.IsNotDistinctFrom)
+		}
+		if l == nil || r == nil {
+			return nil
+		}
+		c, ok := compareIndexValues(l, r)
+		if !ok {
+			return nil
+		}
+		switch n.Operator {
+		case tree.EQ:
+			return c == 0
+		case tree.NE:
+			return c != 0
+		case tree.LT:
+			return c < 0
+		case tree.LE:
+			return c <= 0
+		case tree.GT:
+			return c > 0
+		default:
+			return c >= 0
+		}
+	case *tree.CastExpr:
+		v := evalIndexExpr(n.Expr, row)
+		if v == nil {
+			return nil
+		}
+		if n.Type.Family() == types.DateFamily {
+			return toDate(v)
+		}
+		if t, ok := v.(time.Time); ok {
+			return t.Format(time.RFC3339Nano)
+		}
+		return fmt.Sprint(v)
+	case *tree.FuncExpr:
+		return indexFunctions[strings.ToLower(tree.AsString(&n.Func))](evalIndexExpr(n.Exprs[0], row))
+	}
+
+	return nil
+}
+
+func compareIndexValues(a, b interface{}) (int, bool) {
+	if x, ok := toFloat(a); ok {
+		y, ok := toFloat(b)
+		if !ok {
+			return 0, false
+		}
+		switch {
+		case x < y:
+			return -1, true
+		case x > y:
+			return 1, true
+		}
+		return 0, true
+	}
+
+	switch x := a.(type) {
+	case string:
+		y, ok := b.(string)
+		return strings.Compare(x, y), ok
+	case bool:
+		y, ok := b.(bool)
+		if !ok {
+			return 0, false
+		}
+		if x == y {
+			return 0, true
+		}
+		if !x {
+			return -1, true
+		}
+		return 1, true
+	case time.Time:
+		y, ok := b.(time.Time)
+		if !ok {
+			return 0, false
+		}
+		switch {
+		case x.Before(y):
+			return -1, true
+		case x.After(y):
+			return 1, true
+		}
+		return 0, true
+	}
+
+	return strings.Compare(fmt.Sprint(a), fmt.Sprint(b)), true
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch x := v.(type) {
+	case int:
+		return float64(x), true
+	case int8:
+		return float64(x), true
+	case int16:
+		return float64(x), true
+	case int32:
+		return float64(x), true
+	case int64:
+		return float64(x), true
+	case float32:
+		return float64(x), true
+	case float64:
+		return x, true
+	}
+
+	return 0, false
+}
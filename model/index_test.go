@@ -0,0 +1,71 @@
+package model
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseCreateIndex(t *testing.T) {
+	tests := []struct {
+		stmt string
+		want *IndexDef
+		err  bool
+	}{
+		{
+			stmt: "CREATE INDEX ON users (email)",
+			want: &IndexDef{Table: "users", Elements: []IndexElement{{Column: "email", Offset: 23}}},
+		},
+		{
+			stmt: `create unique index concurrently if not exists users_email on only "Shop".users using btree (lower(email), id)`,
+			want: &IndexDef{Name: "users_email", Schema: "Shop", Table: "users", Unique: true, IfNotExists: true,
+				Elements: []IndexElement{{Expr: "lower(email)", Offset: 93}, {Column: "id", Offset: 107}}},
+		},
+		{
+			stmt: "CREATE UNIQUE INDEX u ON t (a, b) INCLUDE (c) NULLS NOT DISTINCT WITH (fillfactor = 70) TABLESPACE fast",
+			want: &IndexDef{Name: "u", Table: "t", Unique: true, NullsNotDistinct: true,
+				Elements: []IndexElement{{Column: "a", Offset: 28}, {Column: "b", Offset: 31}}},
+		},
+		{
+			stmt: "CREATE UNIQUE INDEX u ON t (a) NULLS DISTINCT WHERE deleted_at IS NULL;",
+			want: &IndexDef{Name: "u", Table: "t", Unique: true, Elements: []IndexElement{{Column: "a", Offset: 28}},
+				Where: "deleted_at IS NULL", WhereOffset: 52},
+		},
+		{stmt: "CREATE INDEX u ON t", err: true},
+		{stmt: "CREATE INDEX u t (a)", err: true},
+		{stmt: "CREATE UNIQUE INDEX u ON t (a) NULLS NOT", err: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseCreateIndex(tt.stmt)
+		if (err != nil) != tt.err {
+			t.Errorf("ParseCreateIndex(%q): error %v, expected error %t", tt.stmt, err, tt.err)
+			continue
+		}
+		if !tt.err && !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseCreateIndex(%q) = %+v, expected %+v", tt.stmt, got, tt.want)
+		}
+	}
+}
+
+func TestUniqueConstraintKey(t *testing.T) {
+	tests := []struct {
+		name  string
+		uc    UniqueConstraint
+		row   map[string]interface{}
+		key   []interface{}
+		found bool
+	}{
+		{name: "values", uc: UniqueConstraint{Columns: []string{"a", "b"}}, row: map[string]interface{}{"a": 1, "b": "x"},
+			key: []interface{}{1, "x"}, found: true},
+		{name: "null is distinct", uc: UniqueConstraint{Columns: []string{"a", "b"}}, row: map[string]interface{}{"a": 1, "b": nil}},
+		{name: "nulls not distinct", uc: UniqueConstraint{Columns: []string{"a", "b"}, NullsNotDistinct: true}, row: map[string]interface{}{"a": 1, "b": nil},
+			key: []interface{}{1, nil}, found: true},
+	}
+
+	for _, tt := range tests {
+		key, ok := tt.uc.Key(tt.row)
+		if ok != tt.found || !reflect.DeepEqual(key, tt.key) {
+			t.Errorf("%s: Key() = %v, %v, expected %v, %v", tt.name, key, ok, tt.key, tt.found)
+		}
+	}
+}
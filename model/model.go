@@ -1,10 +1,12 @@
 package model
 
 import (
+	"fmt"
 	"github.com/auxten/postgresql-parser/pkg/sql/types"
 	"github.com/google/uuid"
 	"github.com/lib/pq/oid"
 	"math/rand"
+	"strings"
 	"time"
 )
 
@@ -14,6 +16,9 @@ func init() {
 
 var letterRunes = []rune("abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ")
 
+// textLengths is the number of lengths of default TEXT values, 0 to 19 letters.
+const textLengths = 20
+
 func RandStringRunes(n int) string {
 	b := make([]rune, n)
 	for i := range b {
@@ -29,16 +34,43 @@ type Column struct {
 	Unique  bool
 
 	GenerationType GenerationType
+	NullRatio      float64
+	// Skew of the popularity of referenced rows, used if the column is a
+	// foreign key column
+	Skew float64
 }
 
-func (c Column) GenerateValue() interface{} {
+// Nullable reports whether the column may hold NULL values.
+func (c Column) Nullable(table *Table) bool {
+	if c.NotNull {
+		return false
+	}
+	for _, column := range table.PrimaryKey {
+		if column == c.Name {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (c Column) GenerateValue(row *RowContext) interface{} {
+	if c.NullRatio > 0 && rand.Float64() < c.NullRatio {
+		return nil
+	}
+
+	return c.GenerateNotNull(row)
+}
+
+// GenerateNotNull generates a value of the column without the NULL ratio.
+func (c Column) GenerateNotNull(row *RowContext) interface{} {
 	if c.GenerationType == nil {
 		switch c.Type.Family() {
 		case types.IntFamily:
 			return rand.Int31()
 		case types.StringFamily:
 			if c.Type.Oid() == oid.T_text {
-				return RandStringRunes(rand.Intn(20))
+				return RandStringRunes(rand.Intn(textLengths))
 			} else {
 				return RandStringRunes(1)
 			}
@@ -62,11 +94,95 @@ func (c Column) GenerateValue() interface{} {
 			return nil
 		}
 	} else {
-		return c.GenerationType.GenerateValue()
+		return c.GenerationType.GenerateValue(row)
+	}
+}
+
+// UniqueConstraint is a unique constraint or a unique index. Keys of an
+// expression index are in Exprs, Where is the predicate of a partial index.
+type UniqueConstraint struct {
+	Name    string
+	Columns []string
+	Exprs   []*IndexExpr
+	Where   *IndexExpr
+	// NullsNotDistinct makes keys with NULL conflict like other values
+	NullsNotDistinct bool
+}
+
+// Key returns the values the constraint compares for the row, ok is false if
+// the row is not covered by the partial index or has NULL in the key and the
+// constraint is not NULLS NOT DISTINCT.
+func (uc *UniqueConstraint) Key(row map[string]interface{}) (key []interface{}, ok bool) {
+	if uc.Where != nil && !uc.Where.Holds(row) {
+		return nil, false
+	}
+
+	if uc.Exprs != nil {
+		for _, expr := range uc.Exprs {
+			key = append(key, expr.Eval(row))
+		}
+	} else {
+		for _, column := range uc.Columns {
+			key = append(key, row[column])
+		}
+	}
+	if !uc.NullsNotDistinct {
+		for _, v := range key {
+			if v == nil {
+				return nil, false
+			}
+		}
+	}
+
+	return key, true
+}
+
+// Plain reports whether the constraint is on columns only, for all rows.
+func (uc *UniqueConstraint) Plain() bool {
+	return uc.Exprs == nil && uc.Where == nil
+}
+
+// Uses reports whether the column is a key or used by the index expressions.
+func (uc *UniqueConstraint) Uses(column string) bool {
+	for _, name := range uc.Columns {
+		if name == column {
+			return true
+		}
+	}
+	if uc.Where != nil {
+		for _, name := range uc.Where.Columns() {
+			if name == column {
+				return true
+			}
+		}
 	}
+
+	return false
 }
 
-type UniqueConstraint = []string
+func (uc *UniqueConstraint) RenameColumn(name, newName string) {
+	for i, column := range uc.Columns {
+		if column == name {
+			uc.Columns[i] = newName
+		}
+	}
+	for _, expr := range uc.Exprs {
+		expr.RenameColumn(name, newName)
+	}
+	if uc.Where != nil {
+		uc.Where.RenameColumn(name, newName)
+	}
+}
+
+// ConstraintName returns the name PostgreSQL gives to a constraint declared
+// without a name, suffix is "pkey", "key" or "fkey".
+func ConstraintName(table string, columns []string, suffix string) string {
+	if suffix == "pkey" {
+		return table + "_pkey"
+	}
+
+	return strings.Join(append([]string{table}, columns...), "_") + "_" + suffix
+}
 
 type ForeignKeyRef struct {
 	Table       *Table
@@ -75,9 +191,45 @@ type ForeignKeyRef struct {
 	Columns     []string
 }
 
-type ForeignKeyConstraint = struct {
+type ForeignKeyConstraint struct {
+	Name    string
 	Columns []string
 	Ref     *ForeignKeyRef
+
+	// NullRatio is the share of rows with NULL in all foreign key columns.
+	NullRatio float64
+	// Skew makes some referenced rows referenced much more often than others,
+	// referenced rows are chosen by Zipf distribution with exponent Skew.
+	Skew float64
+}
+
+// Settings returns the null ratio and the skew of the foreign key, the ones
+// set for its columns are used if the foreign key has none.
+func (fk *ForeignKeyConstraint) Settings(table *Table) (nullRatio, skew float64) {
+	nullRatio, skew = fk.NullRatio, fk.Skew
+	for _, name := range fk.Columns {
+		column, ok := table.Columns[name]
+		if !ok {
+			continue
+		}
+		if fk.NullRatio == 0 && column.NullRatio > nullRatio {
+			nullRatio = column.NullRatio
+		}
+		if fk.Skew == 0 && column.Skew > skew {
+			skew = column.Skew
+		}
+	}
+
+	return nullRatio, skew
+}
+
+// CheckSkew checks the Zipf exponent, it must be greater than 1.
+func CheckSkew(skew float64) error {
+	if skew <= 1 {
+		return fmt.Errorf("invalid skew: %v, expected value greater than 1", skew)
+	}
+
+	return nil
 }
 
 type Table struct {
@@ -85,12 +237,35 @@ type Table struct {
 	Name                  string
 	Columns               map[string]*Column
 	PrimaryKey            []string
+	PrimaryKeyName        string
 	UniqueConstraints     []*UniqueConstraint
 	ForeignKeyConstraints []*ForeignKeyConstraint
 
 	TableGenerationSettings *TableGenerationSettings
 }
 
+// Keys returns the primary key and the unique constraints of the table.
+func (t *Table) Keys() []*UniqueConstraint {
+	keys := make([]*UniqueConstraint, 0, len(t.UniqueConstraints)+1)
+	if len(t.PrimaryKey) > 0 {
+		keys = append(keys, &UniqueConstraint{Name: t.PrimaryKeyName, Columns: t.PrimaryKey})
+	}
+
+	return append(keys, t.UniqueConstraints...)
+}
+
+// ForeignKeyColumns returns the columns taking their values from referenced tables.
+func (t *Table) ForeignKeyColumns() map[string]bool {
+	res := map[string]bool{}
+	for _, fk := range t.ForeignKeyConstraints {
+		for _, column := range fk.Columns {
+			res[column] = true
+		}
+	}
+
+	return res
+}
+
 type Schema struct {
 	Name   string
 	Tables map[string]*Table
@@ -0,0 +1,77 @@
+package model
+
+import (
+	"math/rand"
+)
+
+// Person is a synthetic person shared by all person-related columns
+// (name, surname, patronymic, gender, email, ...) of one row.
+type Person struct {
+	Female     bool
+	Gender     string
+	FirstName  string
+	LastName   string
+	Patronymic string
+	FullName   string
+	Email      string
+}
+
+// RowContext holds state shared by the values of one generated row.
+type RowContext struct {
+	female  *bool
+	persons map[string]*Person
+}
+
+func NewRowContext() *RowContext {
+	return &RowContext{persons: map[string]*Person{}}
+}
+
+// Person returns the person of the row in the given locale. All locales
+// of one row describe a person of the same gender. A locale without a
+// person generator falls back to DefaultLocale.
+func (r *RowContext) Person(locale string) *Person {
+	if p, ok := r.persons[locale]; ok {
+		return p
+	}
+
+	if r.female == nil {
+		female := rand.Intn(2) == 0
+		r.female = &female
+	}
+
+	generate, ok := personGenerators[locale]
+	if !ok {
+		generate = personGenerators[DefaultLocale]
+	}
+	p := generate(*r.female)
+	r.persons[locale] = p
+
+	return p
+}
+
+var personGenerators = map[string]func(female bool) *Person{}
+
+// RegisterPersonGenerator registers a person generator for the locale
+// together with the name, surname, full_name, gender and email presets
+// that take their values from the row's person.
+func RegisterPersonGenerator(locale string, generate func(female bool) *Person) {
+	personGenerators[locale] = generate
+
+	fields := map[string]func(p *Person) string{
+		"name":      func(p *Person) string { return p.FirstName },
+		"surname":   func(p *Person) string { return p.LastName },
+		"full_name": func(p *Person) string { return p.FullName },
+		"gender":    func(p *Person) string { return p.Gender },
+		"email":     func(p *Person) string { return p.Email },
+	}
+	for name, field := range fields {
+		field := field
+		RegisterGenerationPreset(&GenerationPreset{
+			Name:   name,
+			Locale: locale,
+			Generate: func(row *RowContext) interface{} {
+				return field(row.Person(locale))
+			},
+		})
+	}
+}
@@ -0,0 +1,202 @@
+package model
+
+import (
+	"fmt"
+	"github.com/auxten/postgresql-parser/pkg/sql/types"
+	"github.com/go-faker/faker/v4"
+	"golang.org/x/crypto/bcrypt"
+	"math/big"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const DefaultLocale = "en"
+
+var stringFamilies = []types.Family{types.StringFamily}
+
+// GenerationPreset is a named value generator used by "type:<name>" comments.
+// Presets are registered per locale, "<name>_<locale>" selects a locale
+// explicitly, a bare "<name>" selects DefaultLocale unless it is a legacy
+// name.
+type GenerationPreset struct {
+	Name     string
+	Locale   string
+	Families []types.Family
+	Generate func(row *RowContext) interface{}
+}
+
+func (p *GenerationPreset) String() string {
+	if p.Locale == "" {
+		return p.Name
+	}
+
+	return p.Name + "_" + p.Locale
+}
+
+var generationPresets = map[string]*GenerationPreset{}
+
+// legacyPresets keep the values of bare names that were generated before
+// presets got locales, e.g. "phone" keeps "+7 ..." numbers while "phone_en"
+// generates "+1 ..." ones.
+var legacyPresets = map[string]*GenerationPreset{
+	"phone": {
+		Name:     "phone",
+		Families: stringFamilies,
+		Generate: func(*RowContext) interface{} { return "+7 " + faker.Phonenumber() },
+	},
+}
+
+// RegisterGenerationPreset adds a preset to the registry, replacing a
+// previously registered preset with the same name and locale.
+func RegisterGenerationPreset(p *GenerationPreset) {
+	if p.Locale == "" {
+		p.Locale = DefaultLocale
+	}
+	if len(p.Families) == 0 {
+		p.Families = stringFamilies
+	}
+
+	generationPresets[p.String()] = p
+}
+
+func GenerationPresetFromString(s string) (*GenerationPreset, error) {
+	if p, ok := generationPresets[s]; ok {
+		return p, nil
+	}
+	if p, ok := legacyPresets[s]; ok {
+		return p, nil
+	}
+	if p, ok := generationPresets[s+"_"+DefaultLocale]; ok {
+		return p, nil
+	}
+
+	// presets like patronymic exist only in some locales
+	var found *GenerationPreset
+	for _, p := range generationPresets {
+		if p.Name == s {
+			if found != nil {
+				return nil, fmt.Errorf("ambiguous generation preset: %s, specify locale", s)
+			}
+			found = p
+		}
+	}
+	if found != nil {
+		return found, nil
+	}
+
+	return nil, fmt.Errorf("unknown generation preset: %s", s)
+}
+
+// GenerationPresets returns names of all registered presets.
+func GenerationPresets() []string {
+	res := make([]string, 0, len(generationPresets))
+	for name := range generationPresets {
+		res = append(res, name)
+	}
+	sort.Strings(res)
+
+	return res
+}
+
+func registerGenerationPresets(locale string, presets map[string]func() interface{}) {
+	for name, generate := range presets {
+		generate := generate
+		RegisterGenerationPreset(&GenerationPreset{
+			Name:     name,
+			Locale:   locale,
+			Generate: func(*RowContext) interface{} { return generate() },
+		})
+	}
+}
+
+func randomItem(items []string) string {
+	return items[rand.Intn(len(items))]
+}
+
+func randomDigits(n int) string {
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = byte('0' + rand.Intn(10))
+	}
+	return string(b)
+}
+
+// iban builds an IBAN with valid check digits for the given country and BBAN.
+func iban(country, bban string) string {
+	var digits strings.Builder
+	for _, r := range bban + country + "00" {
+		if r >= 'A' && r <= 'Z' {
+			digits.WriteString(strconv.Itoa(int(r-'A') + 10))
+		} else {
+			digits.WriteRune(r)
+		}
+	}
+
+	n, _ := new(big.Int).SetString(digits.String(), 10)
+	check := 98 - new(big.Int).Mod(n, big.NewInt(97)).Int64()
+
+	return fmt.Sprintf("%s%02d%s", country, check, bban)
+}
+
+// creditCard returns a Luhn-valid card number with the given prefix.
+func creditCard(prefix string, length int) string {
+	number := []byte(prefix + randomDigits(length-len(prefix)-1))
+
+	sum := 0
+	for i := len(number) - 1; i >= 0; i-- {
+		d := int(number[i] - '0')
+		if (len(number)-i)%2 == 1 {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+	}
+
+	return string(number) + strconv.Itoa((10-sum%10)%10)
+}
+
+var userAgents = []string{
+	"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/%d.0.%d.%d Safari/537.36",
+	"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/%d.0.%d.%d Safari/537.36",
+	"Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/%d.0.%d.%d Safari/537.36",
+	"Mozilla/5.0 (Linux; Android 13; Pixel 7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/%d.0.%d.%d Mobile Safari/537.36",
+	"Mozilla/5.0 (iPhone; CPU iPhone OS 16_%d like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/16.%d Mobile/15E%d Safari/604.1",
+	"Mozilla/5.0 (Windows NT 10.0; Win64; x64; rv:%d.0) Gecko/20100101 Firefox/%d.%d",
+}
+
+func userAgent() string {
+	return fmt.Sprintf(randomItem(userAgents), 90+rand.Intn(30), rand.Intn(6000), rand.Intn(200))
+}
+
+func passwordHash() interface{} {
+	hash, err := bcrypt.GenerateFromPassword([]byte(faker.Password()), bcrypt.MinCost)
+	if err != nil {
+		return nil
+	}
+
+	return string(hash)
+}
+
+func init() {
+	registerGenerationPresets(DefaultLocale, map[string]func() interface{}{
+		"iban":          func() interface{} { return iban("GB", strings.ToUpper(RandStringRunes(4))+randomDigits(14)) },
+		"credit_card":   func() interface{} { return creditCard(randomItem([]string{"4", "51", "52", "53", "54", "55"}), 16) },
+		"url":           func() interface{} { return faker.URL() },
+		"domain":        func() interface{} { return faker.DomainName() },
+		"username":      func() interface{} { return faker.Username() },
+		"password_hash": passwordHash,
+		"user_agent":    func() interface{} { return userAgent() },
+		"word":          func() interface{} { return faker.Word() },
+		"sentence":      func() interface{} { return faker.Sentence() },
+		"paragraph":     func() interface{} { return faker.Paragraph() },
+		"mac_address":   func() interface{} { return faker.MacAddress() },
+	})
+
+	inet := []types.Family{types.StringFamily, types.INetFamily}
+	RegisterGenerationPreset(&GenerationPreset{Name: "ip", Families: inet, Generate: func(*RowContext) interface{} { return faker.IPv4() }})
+	RegisterGenerationPreset(&GenerationPreset{Name: "ipv6", Families: inet, Generate: func(*RowContext) interface{} { return faker.IPv6() }})
+}
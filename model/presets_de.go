@@ -0,0 +1,59 @@
+package model
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+)
+
+type cityDe struct {
+	Name string
+	Zip  int
+}
+
+var (
+	firstNamesMaleDe   = []string{"Lukas", "Leon", "Finn", "Jonas", "Paul", "Felix", "Maximilian", "Elias", "Noah", "Ben", "Tim", "Jan", "Niklas", "Tobias", "Stefan", "Thomas", "Michael", "Andreas", "Jürgen", "Klaus"}
+	firstNamesFemaleDe = []string{"Mia", "Emma", "Hannah", "Sophia", "Lena", "Lea", "Marie", "Anna", "Laura", "Julia", "Katharina", "Sabine", "Petra", "Ursula", "Monika", "Claudia", "Jana", "Clara", "Greta", "Johanna"}
+	surnamesDe         = []string{"Müller", "Schmidt", "Schneider", "Fischer", "Weber", "Meyer", "Wagner", "Becker", "Schulz", "Hoffmann", "Schäfer", "Koch", "Bauer", "Richter", "Klein", "Wolf", "Schröder", "Neumann", "Schwarz", "Zimmermann", "Braun", "Krüger", "Hofmann", "Hartmann"}
+	citiesDe           = []cityDe{
+		{"Berlin", 10115}, {"Hamburg", 20095}, {"München", 80331}, {"Köln", 50667}, {"Frankfurt am Main", 60311},
+		{"Stuttgart", 70173}, {"Düsseldorf", 40213}, {"Leipzig", 4109}, {"Dortmund", 44135}, {"Essen", 45127},
+		{"Bremen", 28195}, {"Dresden", 1067}, {"Hannover", 30159}, {"Nürnberg", 90402}, {"Bonn", 53111},
+	}
+	streetsDe      = []string{"Hauptstraße", "Bahnhofstraße", "Gartenstraße", "Schulstraße", "Dorfstraße", "Bergstraße", "Lindenstraße", "Kirchstraße", "Waldstraße", "Ringstraße", "Goethestraße", "Schillerstraße", "Mozartstraße", "Am Markt"}
+	companyFormsDe = []string{"GmbH", "GmbH", "AG", "KG", "GmbH & Co. KG", "OHG"}
+	jobTitlesDe    = []string{"Softwareentwickler", "Projektleiter", "Buchhalter", "Vertriebsmitarbeiter", "Geschäftsführer", "Sachbearbeiter", "Ingenieur", "Kaufmann", "Lehrer", "Krankenpfleger", "Elektriker", "Datenanalyst", "Rechtsanwalt"}
+	countriesDe    = []string{"Deutschland", "Österreich", "Schweiz", "Frankreich", "Italien", "Spanien", "Niederlande", "Belgien", "Polen", "Dänemark", "Schweden", "Vereinigte Staaten"}
+	emailDomainsDe = []string{"gmx.de", "web.de", "t-online.de", "gmail.com", "posteo.de"}
+	translitDe     = strings.NewReplacer("ä", "ae", "ö", "oe", "ü", "ue", "ß", "ss", " ", "")
+)
+
+func init() {
+	registerGenerationPresets("de", map[string]func() interface{}{
+		"address": func() interface{} {
+			city := citiesDe[rand.Intn(len(citiesDe))]
+			return fmt.Sprintf("%s %d, %05d %s", randomItem(streetsDe), rand.Intn(120)+1, city.Zip+rand.Intn(100), city.Name)
+		},
+		"street":    func() interface{} { return fmt.Sprintf("%s %d", randomItem(streetsDe), rand.Intn(120)+1) },
+		"city":      func() interface{} { return citiesDe[rand.Intn(len(citiesDe))].Name },
+		"zip":       func() interface{} { return fmt.Sprintf("%05d", citiesDe[rand.Intn(len(citiesDe))].Zip+rand.Intn(100)) },
+		"country":   func() interface{} { return randomItem(countriesDe) },
+		"phone":     func() interface{} { return fmt.Sprintf("+49 15%s %s", randomDigits(1), randomDigits(8)) },
+		"company":   func() interface{} { return fmt.Sprintf("%s %s", randomItem(surnamesDe), randomItem(companyFormsDe)) },
+		"job_title": func() interface{} { return randomItem(jobTitlesDe) },
+		"iban":      func() interface{} { return iban("DE", randomDigits(18)) },
+	})
+
+	RegisterPersonGenerator("de", newPersonDe)
+}
+
+func newPersonDe(female bool) *Person {
+	p := &Person{Female: female, Gender: "männlich", FirstName: randomItem(firstNamesMaleDe), LastName: randomItem(surnamesDe)}
+	if female {
+		p.Gender, p.FirstName = "weiblich", randomItem(firstNamesFemaleDe)
+	}
+	p.FullName = p.FirstName + " " + p.LastName
+	p.Email = fmt.Sprintf("%s.%s@%s", translitDe.Replace(strings.ToLower(p.FirstName)), translitDe.Replace(strings.ToLower(p.LastName)), randomItem(emailDomainsDe))
+
+	return p
+}
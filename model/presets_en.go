@@ -0,0 +1,53 @@
+package model
+
+import (
+	"fmt"
+	"github.com/go-faker/faker/v4"
+	"math/rand"
+	"strings"
+)
+
+var (
+	companySuffixesEn = []string{"Inc.", "LLC", "Ltd.", "Group", "Corp.", "& Sons", "Partners"}
+	jobTitlesEn       = []string{
+		"Software Engineer", "Product Manager", "Data Analyst", "Accountant", "Sales Manager", "Designer",
+		"HR Specialist", "Marketing Manager", "Support Engineer", "Project Manager", "QA Engineer",
+		"Office Manager", "Financial Analyst", "DevOps Engineer", "Lawyer", "Chief Executive Officer",
+	}
+	emailDomainsEn = []string{"gmail.com", "yahoo.com", "outlook.com", "hotmail.com", "icloud.com", "example.com"}
+	countriesEn    = []string{
+		"United States", "United Kingdom", "Canada", "Australia", "Ireland", "New Zealand", "Germany",
+		"France", "Spain", "Italy", "Netherlands", "Sweden", "Norway", "Japan", "Brazil", "India",
+	}
+)
+
+func init() {
+	registerGenerationPresets("en", map[string]func() interface{}{
+		"address": func() interface{} {
+			a := faker.GetRealAddress()
+			return fmt.Sprintf("%s, %s, %s %s", a.Address, a.City, a.State, a.PostalCode)
+		},
+		"street":  func() interface{} { return faker.GetRealAddress().Address },
+		"city":    func() interface{} { return faker.GetRealAddress().City },
+		"zip":     func() interface{} { return faker.GetRealAddress().PostalCode },
+		"country": func() interface{} { return randomItem(countriesEn) },
+		"phone":   func() interface{} { return "+1 " + faker.Phonenumber() },
+		"company": func() interface{} {
+			return fmt.Sprintf("%s %s", faker.LastName(), randomItem(companySuffixesEn))
+		},
+		"job_title": func() interface{} { return randomItem(jobTitlesEn) },
+	})
+
+	RegisterPersonGenerator("en", newPersonEn)
+}
+
+func newPersonEn(female bool) *Person {
+	p := &Person{Female: female, Gender: "male", FirstName: faker.FirstNameMale(), LastName: faker.LastName()}
+	if female {
+		p.Gender, p.FirstName = "female", faker.FirstNameFemale()
+	}
+	p.FullName = p.FirstName + " " + p.LastName
+	p.Email = fmt.Sprintf("%s.%s%d@%s", strings.ToLower(p.FirstName), strings.ToLower(p.LastName), rand.Intn(100), randomItem(emailDomainsEn))
+
+	return p
+}
@@ -0,0 +1,174 @@
+package model
+
+import (
+	"fmt"
+	"github.com/go-faker/faker/v4"
+	"math/rand"
+	"reflect"
+	"strings"
+)
+
+var (
+	citiesRu = []string{
+		"Москва", "Санкт-Петербург", "Новосибирск", "Екатеринбург", "Казань", "Нижний Новгород", "Челябинск",
+		"Самара", "Омск", "Ростов-на-Дону", "Уфа", "Красноярск", "Воронеж", "Пермь", "Волгоград", "Краснодар",
+		"Саратов", "Тюмень", "Ижевск", "Барнаул", "Иркутск", "Ярославль", "Владивосток", "Томск", "Калининград",
+	}
+	streetsRu = []string{
+		"Ленина", "Пушкина", "Гагарина", "Советская", "Мира", "Садовая", "Лесная", "Школьная", "Центральная",
+		"Молодёжная", "Набережная", "Заречная", "Октябрьская", "Кирова", "Московская", "Первомайская",
+		"Комсомольская", "Пролетарская", "Победы", "Чехова", "Толстого", "Лермонтова", "Строителей", "Полевая",
+	}
+	streetTypesRu  = []string{"ул.", "ул.", "ул.", "пр-т", "пер.", "б-р"}
+	companyFormsRu = []string{"ООО", "ООО", "АО", "ПАО", "ЗАО"}
+	companyNamesRu = []string{"Вектор", "Альянс", "Стройинвест", "Техносервис", "Гарант", "Меридиан", "Прогресс", "Восток", "Сфера", "Импульс", "Горизонт", "Северсталь-Трейд", "Ромашка", "Энергия", "Сибирь"}
+	jobTitlesRu    = []string{"Менеджер по продажам", "Бухгалтер", "Программист", "Инженер", "Генеральный директор", "Аналитик", "Юрист", "Дизайнер", "Водитель", "Кассир", "Тестировщик", "Системный администратор", "Специалист по кадрам", "Экономист", "Врач", "Учитель", "Менеджер проекта"}
+	countriesRu    = []string{"Россия", "Беларусь", "Казахстан", "Армения", "Грузия", "Узбекистан", "Киргизия", "Германия", "Франция", "Италия", "Китай", "Турция", "Сербия", "Финляндия", "Япония"}
+	emailDomainsRu = []string{"mail.ru", "yandex.ru", "gmail.com", "rambler.ru", "bk.ru", "inbox.ru"}
+	patronymicsRu  = map[string][2]string{
+		"Лев":     {"Львович", "Львовна"},
+		"Павел":   {"Павлович", "Павловна"},
+		"Пётр":    {"Петрович", "Петровна"},
+		"Яков":    {"Яковлевич", "Яковлевна"},
+		"Михаил":  {"Михайлович", "Михайловна"},
+		"Илья":    {"Ильич", "Ильинична"},
+		"Лука":    {"Лукич", "Лукинична"},
+		"Кузьма":  {"Кузьмич", "Кузьминична"},
+		"Фома":    {"Фомич", "Фоминична"},
+		"Дмитрий": {"Дмитриевич", "Дмитриевна"},
+		"Георгий": {"Георгиевич", "Георгиевна"},
+	}
+	translitRu = map[rune]string{
+		'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ё': "e", 'ж': "zh", 'з': "z", 'и': "i",
+		'й': "y", 'к': "k", 'л': "l", 'м': "m", 'н': "n", 'о': "o", 'п': "p", 'р': "r", 'с': "s", 'т': "t",
+		'у': "u", 'ф': "f", 'х': "kh", 'ц': "ts", 'ч': "ch", 'ш': "sh", 'щ': "shch", 'ъ': "", 'ы': "y", 'ь': "",
+		'э': "e", 'ю': "yu", 'я': "ya",
+	}
+)
+
+func firstNameRu(female bool) string {
+	var s interface{}
+	if female {
+		s, _ = faker.GetPerson().RussianFirstNameFemale(reflect.Value{})
+	} else {
+		s, _ = faker.GetPerson().RussianFirstNameMale(reflect.Value{})
+	}
+
+	return s.(string)
+}
+
+func surnameRu(female bool) string {
+	s, _ := faker.GetPerson().RussianLastNameMale(reflect.Value{})
+	if female {
+		return feminineSurnameRu(s.(string))
+	}
+
+	return s.(string)
+}
+
+// feminineSurnameRu converts a male surname to its female form:
+// Иванов -> Иванова, Достоевский -> Достоевская, Толстой -> Толстая.
+func feminineSurnameRu(s string) string {
+	for _, suffix := range []string{"ов", "ев", "ёв", "ин", "ын"} {
+		if strings.HasSuffix(s, suffix) {
+			return s + "а"
+		}
+	}
+	for _, suffix := range [][2]string{{"ский", "ская"}, {"цкий", "цкая"}, {"ой", "ая"}, {"ый", "ая"}} {
+		if strings.HasSuffix(s, suffix[0]) {
+			return strings.TrimSuffix(s, suffix[0]) + suffix[1]
+		}
+	}
+
+	return s
+}
+
+// patronymicRu derives a patronymic from the father's first name:
+// Иван -> Иванович/Ивановна, Сергей -> Сергеевич/Сергеевна,
+// Игорь -> Игоревич/Игоревна, Никита -> Никитич/Никитична.
+func patronymicRu(father string, female bool) string {
+	idx := 0
+	if female {
+		idx = 1
+	}
+	if p, ok := patronymicsRu[father]; ok {
+		return p[idx]
+	}
+
+	runes := []rune(father)
+	last := runes[len(runes)-1]
+	stem := string(runes[:len(runes)-1])
+	endings := [2]string{"ович", "овна"}
+	switch {
+	case strings.HasSuffix(father, "ий"):
+		stem, endings = string(runes[:len(runes)-2]), [2]string{"ьевич", "ьевна"}
+	case last == 'й' || last == 'ь':
+		endings = [2]string{"евич", "евна"}
+	case last == 'а' || last == 'я':
+		endings = [2]string{"ич", "ична"}
+	case strings.ContainsRune("жшчщц", last):
+		stem, endings = father, [2]string{"евич", "евна"}
+	default:
+		stem = father
+	}
+
+	return stem + endings[idx]
+}
+
+func translitRuString(s string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(s) {
+		if t, ok := translitRu[r]; ok {
+			b.WriteString(t)
+		} else {
+			b.WriteRune(r)
+		}
+	}
+
+	return b.String()
+}
+
+func init() {
+	registerGenerationPresets("ru", map[string]func() interface{}{
+		"address": func() interface{} {
+			return fmt.Sprintf("г. %s, %s %s, д. %d, кв. %d", randomItem(citiesRu), randomItem(streetTypesRu), randomItem(streetsRu), rand.Intn(150)+1, rand.Intn(300)+1)
+		},
+		"street": func() interface{} {
+			return fmt.Sprintf("%s %s, д. %d", randomItem(streetTypesRu), randomItem(streetsRu), rand.Intn(150)+1)
+		},
+		"city":    func() interface{} { return randomItem(citiesRu) },
+		"zip":     func() interface{} { return fmt.Sprintf("%d%s", rand.Intn(6)+1, randomDigits(5)) },
+		"country": func() interface{} { return randomItem(countriesRu) },
+		"phone": func() interface{} {
+			return fmt.Sprintf("+7 9%s %s-%s-%s", randomDigits(2), randomDigits(3), randomDigits(2), randomDigits(2))
+		},
+		"company": func() interface{} {
+			return fmt.Sprintf("%s «%s»", randomItem(companyFormsRu), randomItem(companyNamesRu))
+		},
+		"job_title": func() interface{} { return randomItem(jobTitlesRu) },
+	})
+
+	RegisterPersonGenerator("ru", newPersonRu)
+	RegisterGenerationPreset(&GenerationPreset{
+		Name:     "patronymic",
+		Locale:   "ru",
+		Generate: func(row *RowContext) interface{} { return row.Person("ru").Patronymic },
+	})
+}
+
+func newPersonRu(female bool) *Person {
+	p := &Person{
+		Female:     female,
+		Gender:     "мужской",
+		FirstName:  firstNameRu(female),
+		LastName:   surnameRu(female),
+		Patronymic: patronymicRu(firstNameRu(false), female),
+	}
+	if female {
+		p.Gender = "женский"
+	}
+	p.FullName = fmt.Sprintf("%s %s %s", p.LastName, p.FirstName, p.Patronymic)
+	p.Email = fmt.Sprintf("%s.%s%d@%s", translitRuString(p.FirstName), translitRuString(p.LastName), rand.Intn(100), randomItem(emailDomainsRu))
+
+	return p
+}
@@ -0,0 +1,45 @@
+package model
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGenerationPresetFromString(t *testing.T) {
+	tests := []struct {
+		name   string
+		preset string
+		prefix string
+	}{
+		{name: "phone", preset: "phone", prefix: "+7 "},
+		{name: "phone_en", preset: "phone_en", prefix: "+1 "},
+		{name: "phone_de", preset: "phone_de", prefix: "+49 "},
+	}
+
+	for _, tt := range tests {
+		p, err := GenerationPresetFromString(tt.name)
+		if err != nil {
+			t.Fatalf("GenerationPresetFromString(%q): %v", tt.name, err)
+		}
+		if p.String() != tt.preset {
+			t.Errorf("GenerationPresetFromString(%q) = %s, expected %s", tt.name, p, tt.preset)
+		}
+		if v := p.Generate(NewRowContext()).(string); !strings.HasPrefix(v, tt.prefix) {
+			t.Errorf("%s generated %q, expected prefix %q", tt.name, v, tt.prefix)
+		}
+	}
+
+	if _, err := GenerationPresetFromString("no_such_preset"); err == nil {
+		t.Errorf("unknown preset is found")
+	}
+}
+
+func TestRowContextPersonFallback(t *testing.T) {
+	row := NewRowContext()
+	if p := row.Person("xx"); p == nil || p.FirstName == "" {
+		t.Fatalf("person of unknown locale = %+v, want a %s person", p, DefaultLocale)
+	}
+	if row.Person("xx") != row.Person("xx") {
+		t.Errorf("person of a row is generated again")
+	}
+}
@@ -0,0 +1,341 @@
+package model
+
+import (
+	"fmt"
+	"github.com/auxten/postgresql-parser/pkg/sql/types"
+	"math"
+	"math/rand"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type RowRuleOperator = string
+
+const (
+	RowRuleGreater        RowRuleOperator = ">"
+	RowRuleGreaterOrEqual RowRuleOperator = ">="
+	RowRuleLess           RowRuleOperator = "<"
+	RowRuleLessOrEqual    RowRuleOperator = "<="
+)
+
+var rowRuleReg = regexp.MustCompile(`^\s*("[^"]+"|[A-Za-z_][A-Za-z0-9_$]*)\s*(>=|<=|>|<)\s*("[^"]+"|[A-Za-z_][A-Za-z0-9_$]*)\s*$`)
+
+// RowRule is a relationship between two columns of the same row,
+// e.g. "updated_at >= created_at".
+type RowRule struct {
+	Left     string
+	Operator RowRuleOperator
+	Right    string
+}
+
+func NewRowRuleFromString(s string) (*RowRule, error) {
+	m := rowRuleReg.FindStringSubmatch(s)
+	if m == nil {
+		return nil, fmt.Errorf("invalid rule: %s", s)
+	}
+
+	return &RowRule{
+		Left:     ruleIdentifier(m[1]),
+		Operator: m[2],
+		Right:    ruleIdentifier(m[3]),
+	}, nil
+}
+
+func ruleIdentifier(s string) string {
+	if strings.HasPrefix(s, `"`) {
+		return s[1 : len(s)-1]
+	}
+
+	return strings.ToLower(s)
+}
+
+func (r *RowRule) String() string {
+	return fmt.Sprintf("%s %s %s", r.Left, r.Operator, r.Right)
+}
+
+func (r *RowRule) ValidateTable(table *Table) error {
+	left, ok := table.Columns[r.Left]
+	if !ok {
+		return fmt.Errorf("rule %s: column %s not found", r, r.Left)
+	}
+	right, ok := table.Columns[r.Right]
+	if !ok {
+		return fmt.Errorf("rule %s: column %s not found", r, r.Right)
+	}
+
+	if ruleKind(left.Type) == "" || ruleKind(left.Type) != ruleKind(right.Type) {
+		return fmt.Errorf("rule %s: cannot compare %s with %s", r, left.Type.String(), right.Type.String())
+	}
+
+	return nil
+}
+
+func ruleNumeric(t *types.T) bool {
+	return ruleKind(t) == "number"
+}
+
+// ruleKind returns the kind of values of the type that can be compared by
+// rules: numbers, points in time (dates and timestamps) or times of day.
+// It is empty for types rules do not support.
+func ruleKind(t *types.T) string {
+	switch t.Family() {
+	case types.IntFamily, types.FloatFamily, types.DecimalFamily:
+		return "number"
+	case types.DateFamily, types.TimestampFamily, types.TimestampTZFamily:
+		return "datetime"
+	case types.TimeFamily:
+		return "time"
+	}
+
+	return ""
+}
+
+// Holds reports whether the row satisfies the rule. Like a CHECK
+// constraint, a rule holds when any of its values is NULL.
+func (r *RowRule) Holds(row map[string]interface{}, table *Table) bool {
+	cmp, ok := r.compare(row, table)
+	return !ok || r.satisfied(cmp)
+}
+
+// Enforce makes the row satisfy the rule. Columns in fixed (e.g. filled
+// from foreign keys) are never changed; if both are fixed, Enforce returns
+// false. A violating value of a range generator is drawn again from the part
+// of the range that satisfies the rule, values of other generators are
+// generated again. A violating default value is mirrored around the value it
+// is compared with, so it keeps its distance but moves to the right side.
+func (r *RowRule) Enforce(row map[string]interface{}, fixed map[string]bool, table *Table) bool {
+	cmp, ok := r.compare(row, table)
+	if !ok || r.satisfied(cmp) {
+		return true
+	}
+
+	target, anchor := r.Left, r.Right
+	if fixed[target] {
+		target, anchor = anchor, target
+	}
+	if fixed[target] {
+		return false
+	}
+
+	column := table.Columns[target]
+	a, _ := toRuleValue(row[anchor], table.Columns[anchor].Type)
+	switch generator := column.GenerationType.(type) {
+	case nil:
+	case *GenerationTypeRange:
+		return r.enforceRange(row, target, a, generator, table)
+	default:
+		for try := 0; try < ruleRegenerateTries; try++ {
+			row[target] = generator.GenerateValue(NewRowContext())
+			if r.Holds(row, table) {
+				return true
+			}
+		}
+		return false
+	}
+
+	t, _ := toRuleValue(row[target], column.Type)
+	mirrored := 2*a - t
+	if mirrored == t {
+		// equal values violate only strict operators, move by one unit
+		mirrored = t + ruleStep(column.Type)
+		if (target == r.Left) == (r.Operator == RowRuleLess) {
+			mirrored = t - ruleStep(column.Type)
+		}
+	}
+
+	v, ok := fromRuleValue(mirrored, column.Type)
+	if !ok {
+		return false
+	}
+	row[target] = v
+
+	return r.Holds(row, table)
+}
+
+// ruleRegenerateTries is how many times a value of a generator other than
+// range is generated again to satisfy a rule.
+const ruleRegenerateTries = 10
+
+// enforceRange sets the target column to a random value of its range
+// generator that satisfies the rule against the anchor value a.
+func (r *RowRule) enforceRange(row map[string]interface{}, target string, a float64, generator *GenerationTypeRange, table *Table) bool {
+	t := table.Columns[target].Type
+	step := ruleStep(t)
+	lo, ok := toRuleValue(generator.From, t)
+	if !ok {
+		return false
+	}
+	hi, ok := toRuleValue(generator.To, t)
+	if !ok {
+		return false
+	}
+	// the upper bound of a range is not generated
+	hi -= step
+
+	operator := r.Operator
+	if target == r.Right {
+		operator = map[RowRuleOperator]RowRuleOperator{
+			RowRuleGreater:        RowRuleLess,
+			RowRuleGreaterOrEqual: RowRuleLessOrEqual,
+			RowRuleLess:           RowRuleGreater,
+			RowRuleLessOrEqual:    RowRuleGreaterOrEqual,
+		}[operator]
+	}
+	switch operator {
+	case RowRuleGreater:
+		lo = math.Max(lo, a+step)
+	case RowRuleGreaterOrEqual:
+		lo = math.Max(lo, a)
+	case RowRuleLess:
+		hi = math.Min(hi, a-step)
+	case RowRuleLessOrEqual:
+		hi = math.Min(hi, a)
+	}
+	if lo > hi {
+		return false
+	}
+
+	f := lo + rand.Float64()*(hi-lo)
+	if t.Family() != types.FloatFamily && t.Family() != types.DecimalFamily {
+		f = lo + step*float64(rand.Int63n(int64((hi-lo)/step)+1))
+	}
+	v, ok := fromRuleValue(f, t)
+	if !ok {
+		return false
+	}
+	row[target] = v
+
+	return r.Holds(row, table)
+}
+
+func (r *RowRule) satisfied(cmp int) bool {
+	switch r.Operator {
+	case RowRuleGreater:
+		return cmp > 0
+	case RowRuleGreaterOrEqual:
+		return cmp >= 0
+	case RowRuleLess:
+		return cmp < 0
+	case RowRuleLessOrEqual:
+		return cmp <= 0
+	}
+
+	return false
+}
+
+func (r *RowRule) compare(row map[string]interface{}, table *Table) (int, bool) {
+	left, ok := toRuleValue(row[r.Left], table.Columns[r.Left].Type)
+	if !ok {
+		return 0, false
+	}
+	right, ok := toRuleValue(row[r.Right], table.Columns[r.Right].Type)
+	if !ok {
+		return 0, false
+	}
+
+	switch {
+	case left < right:
+		return -1, true
+	case left > right:
+		return 1, true
+	}
+
+	return 0, true
+}
+
+var ruleTimeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+	"02.01.2006 15:04:05",
+	"02.01.2006",
+	"15:04:05",
+}
+
+// toRuleValue converts a generated value to a float64 so that values of
+// different Go types can be compared: numbers as is, times as unix seconds.
+func toRuleValue(v interface{}, t *types.T) (float64, bool) {
+	switch val := v.(type) {
+	case int:
+		return float64(val), true
+	case int16:
+		return float64(val), true
+	case int32:
+		return float64(val), true
+	case int64:
+		return float64(val), true
+	case float32:
+		return float64(val), true
+	case float64:
+		return val, true
+	case time.Time:
+		return ruleSeconds(val, t), true
+	case string:
+		if ruleNumeric(t) {
+			f, err := strconv.ParseFloat(strings.TrimSpace(val), 64)
+			return f, err == nil
+		}
+		for _, layout := range ruleTimeLayouts {
+			if tm, err := time.Parse(layout, strings.TrimSpace(val)); err == nil {
+				return ruleSeconds(tm, t), true
+			}
+		}
+	}
+
+	return 0, false
+}
+
+func ruleSeconds(tm time.Time, t *types.T) float64 {
+	switch t.Family() {
+	case types.TimeFamily:
+		return float64(tm.Hour()*3600 + tm.Minute()*60 + tm.Second())
+	case types.DateFamily:
+		return float64(time.Date(tm.Year(), tm.Month(), tm.Day(), 0, 0, 0, 0, time.UTC).Unix())
+	}
+
+	return float64(tm.UnixNano()) / float64(time.Second)
+}
+
+func fromRuleValue(f float64, t *types.T) (interface{}, bool) {
+	switch t.Family() {
+	case types.IntFamily:
+		bound := float64(math.MaxInt64)
+		switch t.Width() {
+		case 16:
+			bound = math.MaxInt16
+		case 32:
+			bound = math.MaxInt32
+		}
+		if f > bound || f < -bound {
+			return nil, false
+		}
+		return int64(math.Round(f)), true
+	case types.FloatFamily, types.DecimalFamily:
+		return f, true
+	case types.TimeFamily:
+		if f < 0 || f >= 24*3600 {
+			return nil, false
+		}
+		return time.Date(0, 1, 1, 0, 0, int(f), 0, time.UTC), true
+	case types.DateFamily:
+		return time.Unix(int64(f), 0).UTC(), true
+	case types.TimestampFamily, types.TimestampTZFamily:
+		sec, frac := math.Modf(f)
+		return time.Unix(int64(sec), int64(frac*float64(time.Second))), true
+	}
+
+	return nil, false
+}
+
+func ruleStep(t *types.T) float64 {
+	switch t.Family() {
+	case types.DateFamily:
+		return 24 * 3600
+	case types.FloatFamily, types.DecimalFamily:
+		return 0.01
+	}
+
+	return 1
+}
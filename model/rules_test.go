@@ -0,0 +1,117 @@
+package model
+
+import (
+	"github.com/auxten/postgresql-parser/pkg/sql/types"
+	"testing"
+)
+
+func ruleTable(t *testing.T) *Table {
+	t.Helper()
+	table := &Table{Name: "t", Columns: map[string]*Column{}}
+	for name, typ := range map[string]*types.T{
+		"a": types.Int, "b": types.Int, "f": types.Float, "d": types.Date,
+		"ts": types.Timestamp, "tm": types.Time, "s": types.String,
+	} {
+		table.Columns[name] = &Column{Name: name, Type: typ}
+	}
+
+	return table
+}
+
+func TestRowRuleValidateTable(t *testing.T) {
+	tests := []struct {
+		rule string
+		err  bool
+	}{
+		{rule: "a < b"},
+		{rule: "f >= a"},
+		{rule: "ts > d"},
+		{rule: `"tm" <= tm`},
+		{rule: "a < d", err: true},
+		{rule: "ts > tm", err: true},
+		{rule: "s > a", err: true},
+		{rule: "s > s", err: true},
+		{rule: "a < missing", err: true},
+	}
+
+	table := ruleTable(t)
+	for _, tt := range tests {
+		rule, err := NewRowRuleFromString(tt.rule)
+		if err != nil {
+			t.Fatalf("NewRowRuleFromString(%q): %v", tt.rule, err)
+		}
+		if err := rule.ValidateTable(table); (err != nil) != tt.err {
+			t.Errorf("ValidateTable(%q): error %v, expected error %t", tt.rule, err, tt.err)
+		}
+	}
+}
+
+func TestRowRuleEnforceMirror(t *testing.T) {
+	tests := []struct {
+		rule  string
+		a, b  int64
+		fixed string
+		want  int64
+	}{
+		{rule: "b >= a", a: 10, b: 3, fixed: "a", want: 17},
+		{rule: "b > a", a: 5, b: 5, fixed: "a", want: 6},
+		{rule: "b < a", a: 5, b: 5, fixed: "a", want: 4},
+		{rule: "a <= b", a: 10, b: 3, fixed: "b", want: -4},
+	}
+
+	table := ruleTable(t)
+	for _, tt := range tests {
+		rule, _ := NewRowRuleFromString(tt.rule)
+		row := map[string]interface{}{"a": tt.a, "b": tt.b}
+		if !rule.Enforce(row, map[string]bool{tt.fixed: true}, table) {
+			t.Fatalf("Enforce(%q) failed", tt.rule)
+		}
+		target := "b"
+		if tt.fixed == "b" {
+			target = "a"
+		}
+		if row[target] != tt.want {
+			t.Errorf("Enforce(%q): %s = %v, expected %d", tt.rule, target, row[target], tt.want)
+		}
+	}
+}
+
+func TestRowRuleEnforceRange(t *testing.T) {
+	table := ruleTable(t)
+	generator, err := NewGenerationTypeFromString("range:[1 - 20]", types.Int)
+	if err != nil {
+		t.Fatal(err)
+	}
+	table.Columns["b"].GenerationType = *generator
+
+	rule, _ := NewRowRuleFromString("b > a")
+	for i := 0; i < 100; i++ {
+		row := map[string]interface{}{"a": 15, "b": 3}
+		if !rule.Enforce(row, map[string]bool{"a": true}, table) {
+			t.Fatal("Enforce failed")
+		}
+		if b, _ := toRuleValue(row["b"], types.Int); b < 16 || b > 19 {
+			t.Fatalf("b = %v, expected a value of the range greater than a", row["b"])
+		}
+	}
+
+	row := map[string]interface{}{"a": 19, "b": 3}
+	if rule.Enforce(row, map[string]bool{"a": true}, table) {
+		t.Errorf("Enforce succeeded, but the range has no values greater than %v", row["a"])
+	}
+}
+
+func TestRowRuleEnforceFixed(t *testing.T) {
+	table := ruleTable(t)
+	rule, _ := NewRowRuleFromString("b > a")
+
+	row := map[string]interface{}{"a": 5, "b": 3}
+	if rule.Enforce(row, map[string]bool{"a": true, "b": true}, table) {
+		t.Errorf("Enforce succeeded with both columns fixed")
+	}
+
+	row = map[string]interface{}{"a": 5, "b": nil}
+	if !rule.Enforce(row, map[string]bool{}, table) || row["b"] != nil {
+		t.Errorf("rule with NULL is not satisfied as is: %v", row)
+	}
+}
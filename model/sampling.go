@@ -0,0 +1,182 @@
+package model
+
+import (
+	"github.com/auxten/postgresql-parser/pkg/sql/types"
+	"github.com/lib/pq/oid"
+	"math"
+	"math/rand"
+	"time"
+)
+
+// FiniteGenerationType is a generator with an enumerable set of values,
+// values of unique columns are taken from it without repeats.
+type FiniteGenerationType interface {
+	GenerationType
+	// DomainSize returns the number of distinct values, or -1 if they
+	// cannot be enumerated.
+	DomainSize() int64
+	// ValueAt returns the i-th value, 0 <= i < DomainSize().
+	ValueAt(i int64) interface{}
+}
+
+// Sampler draws indexes from [0, n) without replacement. It is a lazy
+// Fisher–Yates shuffle that stores only the swapped positions.
+type Sampler struct {
+	n       int64
+	taken   int64
+	pending int64
+	swapped map[int64]int64
+}
+
+func NewSampler(n int64) *Sampler {
+	return &Sampler{n: n, swapped: map[int64]int64{}}
+}
+
+func (s *Sampler) at(i int64) int64 {
+	if v, ok := s.swapped[i]; ok {
+		return v
+	}
+	return i
+}
+
+// Peek returns a random index that is not taken yet, ok is false if all
+// indexes are taken. The index stays available until Take is called.
+func (s *Sampler) Peek() (index int64, ok bool) {
+	if s.taken >= s.n {
+		return 0, false
+	}
+
+	s.pending = s.taken + rand.Int63n(s.n-s.taken)
+	return s.at(s.pending), true
+}
+
+// Take takes the index returned by the last Peek.
+func (s *Sampler) Take() {
+	s.swapped[s.pending] = s.at(s.taken)
+	delete(s.swapped, s.taken)
+	s.taken++
+}
+
+func (s *Sampler) Remaining() int64 {
+	return s.n - s.taken
+}
+
+// UniqueGenerator generates distinct values of a column by sampling the
+// domain of its generator without replacement. The domain may be split into
+// strata, a value is taken from a random stratum that is not exhausted.
+type UniqueGenerator struct {
+	strata  []*Sampler
+	valueAt func(stratum int, i int64) interface{}
+	size    int64
+	pending int
+}
+
+// NewUniqueGenerator returns nil if values of the column cannot be enumerated.
+func NewUniqueGenerator(c *Column) *UniqueGenerator {
+	if c.GenerationType == nil && c.Type.Family() == types.StringFamily && c.Type.Oid() == oid.T_text {
+		return newTextGenerator()
+	}
+
+	generator := c.finiteGenerationType()
+	if generator == nil || generator.DomainSize() < 0 {
+		return nil
+	}
+
+	return &UniqueGenerator{
+		strata:  []*Sampler{NewSampler(generator.DomainSize())},
+		valueAt: func(_ int, i int64) interface{} { return generator.ValueAt(i) },
+		size:    generator.DomainSize(),
+	}
+}
+
+// newTextGenerator samples the default TEXT values: strings of 0 to 19
+// letters, with lengths as likely as in the default generation. Lengths
+// with more strings than int64 can count are sampled from a subset.
+func newTextGenerator() *UniqueGenerator {
+	g := &UniqueGenerator{
+		valueAt: func(length int, i int64) interface{} {
+			b := make([]rune, length)
+			for j := range b {
+				b[j] = letterRunes[i%int64(len(letterRunes))]
+				i /= int64(len(letterRunes))
+			}
+			return string(b)
+		},
+	}
+
+	n := int64(1)
+	for length := 0; length < textLengths; length++ {
+		g.strata = append(g.strata, NewSampler(n))
+		g.size = saturatingAdd(g.size, n)
+		if n > math.MaxInt64/int64(len(letterRunes)) {
+			n = math.MaxInt64
+		} else {
+			n *= int64(len(letterRunes))
+		}
+	}
+
+	return g
+}
+
+func saturatingAdd(a, b int64) int64 {
+	if a > math.MaxInt64-b {
+		return math.MaxInt64
+	}
+	return a + b
+}
+
+// DomainSize returns the number of distinct values, math.MaxInt64 if there
+// are more.
+func (g *UniqueGenerator) DomainSize() int64 {
+	return g.size
+}
+
+// Next returns a value that was not taken yet, ok is false if the domain is
+// exhausted. The value is taken by Take once the row is accepted.
+func (g *UniqueGenerator) Next() (interface{}, bool) {
+	open := make([]int, 0, len(g.strata))
+	for i, stratum := range g.strata {
+		if stratum.Remaining() > 0 {
+			open = append(open, i)
+		}
+	}
+	if len(open) == 0 {
+		return nil, false
+	}
+
+	g.pending = open[rand.Intn(len(open))]
+	i, _ := g.strata[g.pending].Peek()
+	return g.valueAt(g.pending, i), true
+}
+
+func (g *UniqueGenerator) Take() {
+	g.strata[g.pending].Take()
+}
+
+// finiteGenerationType returns the generator of the column if it is finite,
+// or the finite one matching the default generation of the column type.
+func (c Column) finiteGenerationType() FiniteGenerationType {
+	if c.GenerationType != nil {
+		generator, _ := c.GenerationType.(FiniteGenerationType)
+		return generator
+	}
+
+	switch c.Type.Family() {
+	case types.BoolFamily:
+		return &GenerationTypeOneof{Values: []interface{}{true, false}}
+	case types.StringFamily:
+		if c.Type.Oid() == oid.T_text {
+			return nil
+		}
+		values := make([]interface{}, 0, len(letterRunes))
+		for _, r := range letterRunes {
+			values = append(values, string(r))
+		}
+		return &GenerationTypeOneof{Values: values}
+	case types.DateFamily:
+		today := time.Now()
+		return &GenerationTypeRange{Type: c.Type, From: today.AddDate(0, 0, -500), To: today.AddDate(0, 0, 500)}
+	}
+
+	return nil
+}
@@ -0,0 +1,44 @@
+package model
+
+import (
+	"testing"
+)
+
+func TestSampler(t *testing.T) {
+	for _, n := range []int64{0, 1, 2, 10, 1000} {
+		s := NewSampler(n)
+		seen := make(map[int64]bool, n)
+		for i := int64(0); i < n; i++ {
+			if s.Remaining() != n-i {
+				t.Fatalf("n=%d: Remaining() = %d, expected %d", n, s.Remaining(), n-i)
+			}
+			index, ok := s.Peek()
+			if !ok {
+				t.Fatalf("n=%d: Peek() is not ok after %d indexes", n, i)
+			}
+			if index < 0 || index >= n || seen[index] {
+				t.Fatalf("n=%d: Peek() = %d is out of range or repeated", n, index)
+			}
+			s.Take()
+			seen[index] = true
+		}
+		if _, ok := s.Peek(); ok {
+			t.Errorf("n=%d: Peek() is ok after all indexes are taken", n)
+		}
+	}
+}
+
+func TestSamplerPeekWithoutTake(t *testing.T) {
+	s := NewSampler(3)
+	taken := map[int64]bool{}
+	for i := 0; i < 100; i++ {
+		index, _ := s.Peek()
+		taken[index] = true
+	}
+	if s.Remaining() != 3 {
+		t.Errorf("Remaining() = %d, expected 3", s.Remaining())
+	}
+	if len(taken) != 3 {
+		t.Errorf("Peek() returned %d distinct indexes, expected 3", len(taken))
+	}
+}
@@ -0,0 +1,229 @@
+package model
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Comment is the text of a comment and its byte offset in the statement.
+type Comment struct {
+	Text   string
+	Offset int
+}
+
+// TableElement is a column or constraint definition of CREATE TABLE
+// together with the comments around it.
+type TableElement struct {
+	Text     string
+	Column   string
+	Comments []Comment
+}
+
+var tableConstraintKeywords = map[string]bool{
+	"constraint": true,
+	"primary":    true,
+	"unique":     true,
+	"foreign":    true,
+	"check":      true,
+	"exclude":    true,
+	"like":       true,
+	"index":      true,
+	"family":     true,
+}
+
+// ScanCreateTable splits the body of a CREATE TABLE statement into elements
+// and assigns comments to them. A comment belongs to an element if it is
+// written inside the element or after it on the same line, comments on
+// their own lines belong to the element that follows them. Comments that
+// belong to no element (before the body, after the last element) are
+// returned separately.
+func ScanCreateTable(stmt string) (elements []*TableElement, dangling []Comment) {
+	var (
+		depth, line, commaLine int
+		bodyStarted, bodyEnded bool
+		cur                    *TableElement
+		curText                strings.Builder
+		curLine                int
+		last                   *TableElement
+		lastLine               int
+		pending                []Comment
+	)
+
+	finish := func() {
+		if cur != nil {
+			cur.Text = strings.TrimSpace(curText.String())
+			cur.Column = elementColumn(cur.Text)
+			elements = append(elements, cur)
+			last, lastLine = cur, curLine
+		}
+		cur = nil
+		curText.Reset()
+	}
+
+	addComment := func(text Comment, startLine int) {
+		switch {
+		case !bodyStarted:
+			dangling = append(dangling, text)
+		case bodyEnded:
+			if last != nil && lastLine == startLine {
+				last.Comments = append(last.Comments, text)
+			} else {
+				dangling = append(dangling, text)
+			}
+		case cur != nil && curLine == startLine:
+			cur.Comments = append(cur.Comments, text)
+		case last != nil && commaLine == startLine:
+			last.Comments = append(last.Comments, text)
+		default:
+			pending = append(pending, text)
+		}
+	}
+
+	content := func(s string) {
+		if !bodyStarted || bodyEnded {
+			return
+		}
+		if cur == nil {
+			cur = &TableElement{}
+		}
+		cur.Comments = append(cur.Comments, pending...)
+		pending = nil
+		curText.WriteString(s)
+		curLine = line
+	}
+
+	space := func() {
+		if cur != nil {
+			curText.WriteString(" ")
+		}
+	}
+
+	runes := []rune(stmt)
+	comment := func(text []rune, start int) Comment {
+		return Comment{Text: string(text), Offset: len(string(runes[:start]))}
+	}
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case r == '\n':
+			line++
+			space()
+		case unicode.IsSpace(r):
+			space()
+		case r == '-' && i+1 < len(runes) && runes[i+1] == '-':
+			start := i
+			for i < len(runes) && runes[i] != '\n' {
+				i++
+			}
+			addComment(comment(runes[start+2:i], start+2), line)
+			i--
+		case r == '/' && i+1 < len(runes) && runes[i+1] == '*':
+			start, startLine, end, nested := i+2, line, len(runes), 0
+			for i += 2; i < len(runes); i++ {
+				if runes[i] == '\n' {
+					line++
+				} else if runes[i] == '/' && i+1 < len(runes) && runes[i+1] == '*' {
+					nested++
+					i++
+				} else if runes[i] == '*' && i+1 < len(runes) && runes[i+1] == '/' {
+					if nested == 0 {
+						end = i
+						i++
+						break
+					}
+					nested--
+					i++
+				}
+			}
+			addComment(comment(blockCommentText(runes[start:end]), start), startLine)
+		case r == '\'' || r == '"':
+			start := i
+			for i++; i < len(runes); i++ {
+				if runes[i] == '\n' {
+					line++
+				}
+				if runes[i] == r {
+					if i+1 < len(runes) && runes[i+1] == r {
+						i++
+						continue
+					}
+					break
+				}
+			}
+			end := i + 1
+			if end > len(runes) {
+				end = len(runes)
+			}
+			content(string(runes[start:end]))
+		case r == '(':
+			if !bodyStarted {
+				bodyStarted = true
+				depth = 1
+				continue
+			}
+			depth++
+			content("(")
+		case r == ')':
+			depth--
+			if depth == 0 && bodyStarted && !bodyEnded {
+				finish()
+				bodyEnded = true
+				dangling = append(dangling, pending...)
+				pending = nil
+				continue
+			}
+			content(")")
+		case r == ',' && depth == 1 && bodyStarted && !bodyEnded:
+			finish()
+			commaLine = line
+		default:
+			content(string(r))
+		}
+	}
+
+	finish()
+	dangling = append(dangling, pending...)
+
+	return elements, dangling
+}
+
+// blockCommentText joins lines of a block comment and drops the leading
+// asterisks, keeping offsets of the rest of the text.
+func blockCommentText(s []rune) []rune {
+	res := make([]rune, len(s))
+	lineStart := true
+	for i, r := range s {
+		switch {
+		case r == '\n':
+			r, lineStart = ' ', true
+		case lineStart && r == '*':
+			r, lineStart = ' ', false
+		case !unicode.IsSpace(r):
+			lineStart = false
+		}
+		res[i] = r
+	}
+
+	return res
+}
+
+// elementColumn returns the column name of a column definition, or an
+// empty string for table constraints.
+func elementColumn(def string) string {
+	if strings.HasPrefix(def, `"`) {
+		end := strings.Index(def[1:], `"`)
+		if end < 0 {
+			return ""
+		}
+		return def[1 : end+1]
+	}
+
+	words := strings.FieldsFunc(def, func(r rune) bool {
+		return !(unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '$')
+	})
+	if len(words) == 0 || tableConstraintKeywords[strings.ToLower(words[0])] {
+		return ""
+	}
+
+	return strings.ToLower(words[0])
+}
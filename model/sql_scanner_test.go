@@ -0,0 +1,94 @@
+package model
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestScanCreateTable(t *testing.T) {
+	tests := []struct {
+		name     string
+		stmt     string
+		elements map[string][]string
+		dangling []string
+	}{
+		{
+			name: "same line and preceding lines",
+			stmt: "CREATE TABLE items\n( -- count:50\n    id INT PRIMARY KEY,\n    -- type:word\n    title TEXT,\n    price INT -- range:[1 - 100]\n)",
+			elements: map[string][]string{
+				"id":    {"count:50"},
+				"title": {"type:word"},
+				"price": {"range:[1 - 100]"},
+			},
+		},
+		{
+			name: "comments outside of the body",
+			stmt: "CREATE TABLE t -- count:5\n(\n    a INT\n    -- rule:a > 0\n)",
+			elements: map[string][]string{
+				"a": nil,
+			},
+			dangling: []string{"count:5", "rule:a > 0"},
+		},
+		{
+			name: "comment after comma belongs to previous element",
+			stmt: "CREATE TABLE t (\n    a INT, -- null:0.1\n    b INT /* unique */\n)",
+			elements: map[string][]string{
+				"a": {"null:0.1"},
+				"b": {"unique"},
+			},
+		},
+		{
+			name: "multiline definition",
+			stmt: "CREATE TABLE t (\n    a NUMERIC(10,\n        2) -- range:[1 - 9]\n)",
+			elements: map[string][]string{
+				"a": {"range:[1 - 9]"},
+			},
+		},
+		{
+			name: "quoted names and constraints",
+			stmt: "CREATE TABLE t (\n    \"Order\" INT, -- unique\n    PRIMARY KEY (\"Order\")\n)",
+			elements: map[string][]string{
+				"Order": {"unique"},
+				"":      nil,
+			},
+		},
+		{
+			name: "block comment with asterisks",
+			stmt: "CREATE TABLE t (\n    /*\n     * type:word\n     */\n    a TEXT\n)",
+			elements: map[string][]string{
+				"a": {"type:word"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			elements, dangling := ScanCreateTable(tt.stmt)
+			got := make(map[string][]string, len(elements))
+			for _, e := range elements {
+				got[e.Column] = commentTexts(tt.stmt, e.Comments)
+			}
+			if !reflect.DeepEqual(got, tt.elements) {
+				t.Errorf("elements = %q, expected %q", got, tt.elements)
+			}
+			if d := commentTexts(tt.stmt, dangling); !reflect.DeepEqual(d, tt.dangling) {
+				t.Errorf("dangling = %q, expected %q", d, tt.dangling)
+			}
+		})
+	}
+}
+
+// commentTexts returns trimmed comment texts and checks their offsets.
+func commentTexts(stmt string, comments []Comment) []string {
+	var res []string
+	for _, c := range comments {
+		text := strings.TrimSpace(c.Text)
+		res = append(res, text)
+		if !strings.Contains(stmt[c.Offset:], text) {
+			res = append(res, "bad offset")
+		}
+	}
+
+	return res
+}
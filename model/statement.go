@@ -0,0 +1,215 @@
+package model
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Statement is one SQL statement of a source file. Line and Column are
+// 1-based position of its first character in the file.
+type Statement struct {
+	File   string
+	Text   string
+	Line   int
+	Column int
+}
+
+// SplitStatements splits SQL source into statements by semicolons outside of
+// quotes and comments. Comments between statements are dropped, comments
+// inside a statement are kept.
+func SplitStatements(file, src string) []Statement {
+	var res []Statement
+	line, column := 1, 1
+	start, startLine, startColumn := -1, 0, 0
+
+	advance := func(s string) {
+		for _, r := range s {
+			if r == '\n' {
+				line++
+				column = 1
+			} else {
+				column++
+			}
+		}
+	}
+	finish := func(end int) {
+		if start >= 0 {
+			text := strings.TrimRightFunc(src[start:end], unicode.IsSpace)
+			res = append(res, Statement{File: file, Text: text, Line: startLine, Column: startColumn})
+		}
+		start = -1
+	}
+
+	for i := 0; i < len(src); {
+		r, size := utf8.DecodeRuneInString(src[i:])
+		end := i + size
+
+		switch {
+		case strings.HasPrefix(src[i:], "--"):
+			end = strings.IndexByte(src[i:], '\n')
+			if end < 0 {
+				end = len(src)
+			} else {
+				end += i
+			}
+		case strings.HasPrefix(src[i:], "/*"):
+			end = blockCommentEnd(src, i)
+		case r == '\'' || r == '"':
+			end = quoteEnd(src, i)
+		case r == '$':
+			if tag := dollarTag(src[i:]); tag != "" && (i == 0 || !isIdentRune(lastRune(src[:i]))) {
+				end = strings.Index(src[i+len(tag):], tag)
+				if end < 0 {
+					end = len(src)
+				} else {
+					end += i + 2*len(tag)
+				}
+			}
+		case r == ';':
+			finish(i)
+			advance(src[i:end])
+			i = end
+			continue
+		}
+
+		if start < 0 && !unicode.IsSpace(r) && !strings.HasPrefix(src[i:], "--") && !strings.HasPrefix(src[i:], "/*") {
+			start, startLine, startColumn = i, line, column
+		}
+		advance(src[i:end])
+		i = end
+	}
+	finish(len(src))
+
+	return res
+}
+
+func blockCommentEnd(src string, i int) int {
+	nested := 0
+	for i += 2; i < len(src); i++ {
+		if strings.HasPrefix(src[i:], "/*") {
+			nested++
+			i++
+		} else if strings.HasPrefix(src[i:], "*/") {
+			if nested == 0 {
+				return i + 2
+			}
+			nested--
+			i++
+		}
+	}
+
+	return len(src)
+}
+
+func quoteEnd(src string, i int) int {
+	quote := src[i]
+	for i++; i < len(src); i++ {
+		if src[i] == quote {
+			if i+1 < len(src) && src[i+1] == quote {
+				i++
+				continue
+			}
+			return i + 1
+		}
+	}
+
+	return len(src)
+}
+
+// dollarTag returns the opening tag of a dollar-quoted string like $$ or $body$.
+func dollarTag(s string) string {
+	for i := 1; i < len(s); i++ {
+		if s[i] == '$' {
+			return s[:i+1]
+		}
+		if !(s[i] == '_' || s[i] >= 'a' && s[i] <= 'z' || s[i] >= 'A' && s[i] <= 'Z' || i > 1 && s[i] >= '0' && s[i] <= '9') {
+			return ""
+		}
+	}
+
+	return ""
+}
+
+func isIdentRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '$'
+}
+
+func lastRune(s string) rune {
+	r, _ := utf8.DecodeLastRuneInString(s)
+	return r
+}
+
+// HasPrefix reports whether the statement starts with the given keywords,
+// case and whitespace between keywords are ignored.
+func (s Statement) HasPrefix(keywords string) bool {
+	words := strings.Fields(keywords)
+	rest := s.Text
+	for _, word := range words {
+		rest = strings.TrimLeftFunc(rest, unicode.IsSpace)
+		if len(rest) < len(word) || !strings.EqualFold(rest[:len(word)], word) {
+			return false
+		}
+		rest = rest[len(word):]
+		if r, _ := utf8.DecodeRuneInString(rest); rest != "" && isIdentRune(r) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Find returns the byte offset of the first occurrence of the identifier
+// name in the statement, or 0 if it is not found.
+func (s Statement) Find(name string) int {
+	if name == "" {
+		return 0
+	}
+
+	text := strings.ToLower(s.Text)
+	name = strings.ToLower(name)
+	for from := 0; from < len(text); {
+		i := strings.Index(text[from:], name)
+		if i < 0 {
+			break
+		}
+		i += from
+		end := i + len(name)
+		if (i == 0 || !isIdentRune(lastRune(text[:i]))) &&
+			(end == len(text) || !isIdentRune(rune(text[end]))) {
+			return i
+		}
+		from = i + 1
+	}
+
+	return 0
+}
+
+// Position returns the line and column in the source file of the byte
+// offset in the statement.
+func (s Statement) Position(offset int) (line, column int) {
+	if offset < 0 || offset > len(s.Text) {
+		offset = 0
+	}
+
+	before := s.Text[:offset]
+	line = s.Line + strings.Count(before, "\n")
+	if i := strings.LastIndexByte(before, '\n'); i >= 0 {
+		return line, utf8.RuneCountInString(before[i+1:]) + 1
+	}
+
+	return line, s.Column + utf8.RuneCountInString(before)
+}
+
+// Diagnostic creates a diagnostic pointing to the byte offset in the statement.
+func (s Statement) Diagnostic(severity Severity, code string, offset int, message string) *Diagnostic {
+	line, column := s.Position(offset)
+	return &Diagnostic{
+		File:     s.File,
+		Line:     line,
+		Column:   column,
+		Severity: severity,
+		Code:     code,
+		Message:  message,
+	}
+}
@@ -0,0 +1,68 @@
+package model
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitStatements(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want []Statement
+	}{
+		{
+			name: "simple",
+			src:  "CREATE TABLE a (id INT);\nCREATE TABLE b (id INT);",
+			want: []Statement{
+				{File: "f.sql", Text: "CREATE TABLE a (id INT)", Line: 1, Column: 1},
+				{File: "f.sql", Text: "CREATE TABLE b (id INT)", Line: 2, Column: 1},
+			},
+		},
+		{
+			name: "comments between statements are dropped",
+			src:  "-- header;\n/* block; */ SELECT 1;\n  -- trailer",
+			want: []Statement{{File: "f.sql", Text: "SELECT 1", Line: 2, Column: 14}},
+		},
+		{
+			name: "comments inside statement are kept",
+			src:  "CREATE TABLE a\n( -- count:5;\n  id INT /* ; */\n);",
+			want: []Statement{{File: "f.sql", Text: "CREATE TABLE a\n( -- count:5;\n  id INT /* ; */\n)", Line: 1, Column: 1}},
+		},
+		{
+			name: "quotes",
+			src:  `COMMENT ON TABLE a IS 'it''s; fine'; CREATE TABLE "x;y" (id INT)`,
+			want: []Statement{
+				{File: "f.sql", Text: `COMMENT ON TABLE a IS 'it''s; fine'`, Line: 1, Column: 1},
+				{File: "f.sql", Text: `CREATE TABLE "x;y" (id INT)`, Line: 1, Column: 38},
+			},
+		},
+		{
+			name: "dollar quotes",
+			src:  "CREATE FUNCTION f() AS $body$ SELECT 1; $body$;\nSELECT $1;",
+			want: []Statement{
+				{File: "f.sql", Text: "CREATE FUNCTION f() AS $body$ SELECT 1; $body$", Line: 1, Column: 1},
+				{File: "f.sql", Text: "SELECT $1", Line: 2, Column: 1},
+			},
+		},
+		{
+			name: "nested block comments",
+			src:  "/* a /* b; */ c; */ SELECT 1",
+			want: []Statement{{File: "f.sql", Text: "SELECT 1", Line: 1, Column: 21}},
+		},
+		{
+			name: "empty statements",
+			src:  ";;\n;",
+			want: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := SplitStatements("f.sql", tt.src)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SplitStatements(%q) = %+v, expected %+v", tt.src, got, tt.want)
+			}
+		})
+	}
+}
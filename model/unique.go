@@ -0,0 +1,251 @@
+package model
+
+import (
+	"encoding/hex"
+	"fmt"
+	"github.com/auxten/postgresql-parser/pkg/sql/types"
+	"github.com/google/uuid"
+	"github.com/jackc/pgtype"
+	"math"
+	"math/big"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// UniqueSet holds the keys of the rows generated so far for a unique
+// constraint. Keys are normalized the way PostgreSQL compares the values of
+// the column types, so equal values of different Go types collide.
+type UniqueSet struct {
+	constraint *UniqueConstraint
+	types      []*types.T
+	keys       map[string]struct{}
+}
+
+func NewUniqueSet(table *Table, uc *UniqueConstraint) *UniqueSet {
+	s := &UniqueSet{constraint: uc, keys: map[string]struct{}{}}
+	if uc.Exprs == nil {
+		for _, name := range uc.Columns {
+			s.types = append(s.types, columnType(table, name))
+		}
+		return s
+	}
+
+	for _, expr := range uc.Exprs {
+		var t *types.T
+		if name, ok := expr.Column(); ok {
+			t = columnType(table, name)
+		}
+		s.types = append(s.types, t)
+	}
+
+	return s
+}
+
+func columnType(table *Table, name string) *types.T {
+	if column, ok := table.Columns[name]; ok {
+		return column.Type
+	}
+
+	return nil
+}
+
+// Contains reports whether a row with the same key was added. Rows outside
+// of a partial index never conflict, rows with NULL in the key conflict only
+// if the constraint is NULLS NOT DISTINCT.
+func (s *UniqueSet) Contains(row map[string]interface{}) bool {
+	key, ok := s.key(row)
+	if !ok {
+		return false
+	}
+
+	_, found := s.keys[key]
+	return found
+}
+
+func (s *UniqueSet) Add(row map[string]interface{}) {
+	if key, ok := s.key(row); ok {
+		s.keys[key] = struct{}{}
+	}
+}
+
+func (s *UniqueSet) Len() int {
+	return len(s.keys)
+}
+
+func (s *UniqueSet) key(row map[string]interface{}) (string, bool) {
+	values, ok := s.constraint.Key(row)
+	if !ok || len(values) == 0 {
+		return "", false
+	}
+
+	var b strings.Builder
+	for i, v := range values {
+		if v == nil {
+			// a length never starts with "-", so NULL differs from any value
+			b.WriteString("-:")
+			continue
+		}
+		part := NormalizeValue(v, s.types[i])
+		b.WriteString(strconv.Itoa(len(part)))
+		b.WriteByte(':')
+		b.WriteString(part)
+	}
+
+	return b.String(), true
+}
+
+// NormalizeValue returns a string equal for the values PostgreSQL stores as
+// equal in a column of type t, t may be nil if the type is unknown.
+func NormalizeValue(v interface{}, t *types.T) string {
+	family := types.AnyFamily
+	if t != nil {
+		family = t.Family()
+	}
+
+	switch x := v.(type) {
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return "n" + fmt.Sprint(x)
+	case float32:
+		return normalizeFloat(float64(x), t)
+	case float64:
+		return normalizeFloat(x, t)
+	case string:
+		if parsed, ok := parseValue(x, t); ok {
+			return NormalizeValue(parsed, t)
+		}
+		return "s" + x
+	case bool:
+		return "b" + strconv.FormatBool(x)
+	case uuid.UUID:
+		return "u" + x.String()
+	case [16]byte:
+		return "u" + uuid.UUID(x).String()
+	case []byte:
+		return "x" + hex.EncodeToString(x)
+	case time.Duration:
+		return "d" + strconv.FormatInt(int64(x/time.Microsecond), 10)
+	case time.Time:
+		// PostgreSQL keeps microseconds
+		x = x.Round(time.Microsecond)
+		switch family {
+		case types.DateFamily:
+			return "t" + x.Format("2006-01-02")
+		case types.TimeFamily:
+			return "t" + x.Format("15:04:05.000000")
+		case types.TimeTZFamily:
+			return "t" + x.Format("15:04:05.000000Z07:00")
+		case types.TimestampFamily:
+			// the wall clock is stored, the zone is dropped
+			return "t" + x.Format("2006-01-02T15:04:05.000000")
+		}
+		return "t" + x.UTC().Format("2006-01-02T15:04:05.000000")
+	case net.IP:
+		return normalizeInet(&net.IPNet{IP: x, Mask: net.CIDRMask(len(x)*8, len(x)*8)})
+	case *net.IPNet:
+		return normalizeInet(x)
+	case pgtype.Numeric:
+		if x.Status != pgtype.Present || x.NaN || x.InfinityModifier != pgtype.None {
+			break
+		}
+		if x.Exp >= 0 {
+			n := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(x.Exp)), nil)
+			return "n" + n.Mul(n, x.Int).String()
+		}
+		var f float64
+		if err := x.AssignTo(&f); err == nil {
+			return normalizeFloat(f, t)
+		}
+	case pgtype.Interval:
+		// PostgreSQL compares intervals with 30-day months and 24-hour days
+		days := int64(x.Months)*30 + int64(x.Days)
+		return "d" + strconv.FormatInt(days*24*int64(time.Hour/time.Microsecond)+x.Microseconds, 10)
+	}
+
+	return fmt.Sprintf("%T:%v", v, v)
+}
+
+var parseTimeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02",
+	"02.01.2006 15:04:05",
+	"02.01.2006",
+	"15:04:05.999999999Z07:00",
+	"15:04:05.999999999",
+}
+
+// parseValue parses a string value, e.g. of a oneof generator, as
+// PostgreSQL parses it for a column of type t.
+func parseValue(s string, t *types.T) (interface{}, bool) {
+	if t == nil {
+		return nil, false
+	}
+
+	s = strings.TrimSpace(s)
+	switch t.Family() {
+	case types.IntFamily:
+		if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return n, true
+		}
+	case types.FloatFamily, types.DecimalFamily:
+		if f, err := strconv.ParseFloat(s, 64); err == nil {
+			return f, true
+		}
+	case types.BoolFamily:
+		switch strings.ToLower(s) {
+		case "t", "true", "y", "yes", "on", "1":
+			return true, true
+		case "f", "false", "n", "no", "off", "0":
+			return false, true
+		}
+	case types.UuidFamily:
+		if id, err := uuid.Parse(s); err == nil {
+			return id, true
+		}
+	case types.INetFamily:
+		if ip, ipNet, err := net.ParseCIDR(s); err == nil {
+			ipNet.IP = ip
+			return ipNet, true
+		}
+		if ip := net.ParseIP(s); ip != nil {
+			return ip, true
+		}
+	case types.DateFamily, types.TimeFamily, types.TimeTZFamily, types.TimestampFamily, types.TimestampTZFamily:
+		for _, layout := range parseTimeLayouts {
+			if tm, err := time.Parse(layout, s); err == nil {
+				return tm, true
+			}
+		}
+	}
+
+	return nil, false
+}
+
+// normalizeInet keeps the address with its prefix length, IPv4 addresses
+// in the 4-byte form.
+func normalizeInet(n *net.IPNet) string {
+	ip := n.IP
+	if v4 := ip.To4(); v4 != nil {
+		ip = v4
+	}
+	ones, _ := n.Mask.Size()
+	if ones == len(n.IP)*8 {
+		ones = len(ip) * 8
+	}
+
+	return "i" + ip.String() + "/" + strconv.Itoa(ones)
+}
+
+func normalizeFloat(f float64, t *types.T) string {
+	if t != nil && t.Family() == types.FloatFamily && t.Width() == 32 {
+		f = float64(float32(f))
+	}
+	if f == math.Trunc(f) && math.Abs(f) < 1e15 {
+		return "n" + strconv.FormatInt(int64(f), 10)
+	}
+
+	return "n" + strconv.FormatFloat(f, 'g', -1, 64)
+}
@@ -0,0 +1,81 @@
+package model
+
+import (
+	"github.com/auxten/postgresql-parser/pkg/sql/types"
+	"github.com/jackc/pgtype"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestNormalizeValue(t *testing.T) {
+	moscow := time.FixedZone("MSK", 3*3600)
+	instant := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name  string
+		a, b  interface{}
+		t     *types.T
+		equal bool
+	}{
+		{name: "int32 and string", a: int32(1), b: "1", t: types.Int, equal: true},
+		{name: "int64 and float64", a: int64(1), b: float64(1), t: types.Int, equal: true},
+		{name: "different ints", a: "1", b: int16(2), t: types.Int4},
+		{name: "numeric and float", a: pgtype.Numeric{Int: big.NewInt(15), Exp: -1, Status: pgtype.Present}, b: 1.5, t: types.Decimal, equal: true},
+		{name: "numeric with exponent and int", a: pgtype.Numeric{Int: big.NewInt(12), Exp: 2, Status: pgtype.Present}, b: 1200, t: types.Decimal, equal: true},
+		{name: "numeric and string", a: pgtype.Numeric{Int: big.NewInt(25), Exp: -1, Status: pgtype.Present}, b: " 2.5 ", t: types.Decimal, equal: true},
+		{name: "real keeps float32 precision", a: float32(0.1), b: 0.1, t: types.Float4, equal: true},
+		{name: "timestamptz in other zone", a: instant, b: instant.In(moscow), t: types.TimestampTZ, equal: true},
+		{name: "timestamp keeps wall clock", a: instant, b: instant.In(moscow), t: types.Timestamp},
+		{name: "timestamp and string", a: instant, b: "2024-01-01 12:00:00", t: types.Timestamp, equal: true},
+		{name: "timestamp microseconds", a: instant, b: instant.Add(100 * time.Nanosecond), t: types.Timestamp, equal: true},
+		{name: "date drops time", a: instant, b: instant.Add(5 * time.Hour), t: types.Date, equal: true},
+		{name: "bool and string", a: true, b: "yes", t: types.Bool, equal: true},
+		{name: "case of text", a: "a", b: "A", t: types.String},
+		{name: "inet forms", a: net.ParseIP("10.0.0.1"), b: "10.0.0.1", t: types.INet, equal: true},
+		{name: "interval and duration", a: pgtype.Interval{Days: 1, Status: pgtype.Present}, b: 24 * time.Hour, t: types.Interval, equal: true},
+		{name: "unknown type", a: int32(1), b: "1"},
+	}
+
+	for _, tt := range tests {
+		a, b := NormalizeValue(tt.a, tt.t), NormalizeValue(tt.b, tt.t)
+		if (a == b) != tt.equal {
+			t.Errorf("%s: %q and %q, expected equal %v", tt.name, a, b, tt.equal)
+		}
+	}
+}
+
+func TestUniqueSet(t *testing.T) {
+	table := &Table{Name: "t", Columns: map[string]*Column{
+		"a": {Name: "a", Type: types.Int},
+		"b": {Name: "b", Type: types.String},
+	}}
+	row := func(a, b interface{}) map[string]interface{} {
+		return map[string]interface{}{"a": a, "b": b}
+	}
+
+	s := NewUniqueSet(table, &UniqueConstraint{Columns: []string{"a", "b"}})
+	s.Add(row(int32(1), "x"))
+	s.Add(row(int32(1), nil))
+	if s.Len() != 1 {
+		t.Errorf("Len() = %d, expected 1, keys with NULL are not kept", s.Len())
+	}
+	if !s.Contains(row("1", "x")) {
+		t.Errorf("row equal by value is not found")
+	}
+	if s.Contains(row(2, "x")) || s.Contains(row(1, "X")) {
+		t.Errorf("different row is found")
+	}
+	if s.Contains(row(1, nil)) {
+		t.Errorf("row with NULL conflicts")
+	}
+
+	s = NewUniqueSet(table, &UniqueConstraint{Columns: []string{"a", "b"}, NullsNotDistinct: true})
+	s.Add(row(1, nil))
+	if !s.Contains(row(int64(1), nil)) {
+		t.Errorf("row with NULL does not conflict with NULLS NOT DISTINCT")
+	}
+	if s.Contains(row(1, "")) || s.Contains(row(1, "-")) {
+		t.Errorf("NULL conflicts with a string")
+	}
+}
@@ -0,0 +1,422 @@
+package walker
+
+import (
+	"fmt"
+	"github.com/auxten/postgresql-parser/pkg/sql/sem/tree"
+	"github.com/levtul/tmp/model"
+)
+
+func (w *Walker) addColumn(stmt model.Statement, table *model.Table, d *tree.ColumnTableDef) bool {
+	name := string(d.Name)
+	if _, ok := table.Columns[name]; ok {
+		w.addError(stmt, model.CodeDuplicate, stmt.Find(name), fmt.Errorf("column %s already declared", name))
+		return false
+	}
+
+	column := &model.Column{
+		Name:    name,
+		Type:    d.Type,
+		NotNull: d.Nullable.Nullability == tree.NotNull,
+	}
+	table.Columns[name] = column
+
+	if d.PrimaryKey.IsPrimaryKey {
+		table.PrimaryKey = append(table.PrimaryKey, name)
+		table.PrimaryKeyName = model.ConstraintName(table.Name, nil, "pkey")
+	}
+	if d.Unique {
+		addUnique(table, string(d.UniqueConstraintName), []string{name})
+	}
+	if len(d.CheckExprs) > 0 {
+		w.addWarning(stmt, model.CodeUnsupported, stmt.Find("check"), "check constraints are not supported, program may fail")
+	}
+	// constraints of columns added by ALTER TABLE are not hoisted
+	if d.HasFKConstraint() {
+		fk := &tree.ForeignKeyConstraintTableDef{
+			Name:     d.References.ConstraintName,
+			Table:    *d.References.Table,
+			FromCols: tree.NameList{d.Name},
+		}
+		if d.References.Col != "" {
+			fk.ToCols = tree.NameList{d.References.Col}
+		}
+		w.addForeignKey(stmt, table, fk)
+	}
+
+	return true
+}
+
+func (w *Walker) addConstraint(stmt model.Statement, table *model.Table, def tree.ConstraintTableDef) bool {
+	switch d := def.(type) {
+	case *tree.UniqueConstraintTableDef:
+		columns := make([]string, 0, len(d.Columns))
+		for _, column := range d.Columns {
+			if _, ok := w.findColumn(stmt, table, column.Column); !ok {
+				return false
+			}
+			columns = append(columns, string(column.Column))
+		}
+
+		if d.PrimaryKey {
+			table.PrimaryKey = columns
+			table.PrimaryKeyName = string(d.Name)
+			if table.PrimaryKeyName == "" {
+				table.PrimaryKeyName = model.ConstraintName(table.Name, nil, "pkey")
+			}
+		} else {
+			addUnique(table, string(d.Name), columns)
+		}
+	case *tree.ForeignKeyConstraintTableDef:
+		w.addForeignKey(stmt, table, d)
+	case *tree.CheckConstraintTableDef:
+		w.addWarning(stmt, model.CodeUnsupported, stmt.Find("check"), "check constraints are not supported, program may fail")
+	}
+
+	return true
+}
+
+func addUnique(table *model.Table, name string, columns []string) {
+	if name == "" {
+		name = model.ConstraintName(table.Name, columns, "key")
+	}
+	table.UniqueConstraints = append(table.UniqueConstraints, &model.UniqueConstraint{Name: name, Columns: columns})
+	updateUniqueColumns(table)
+}
+
+// updateUniqueColumns marks columns that have a single-column unique
+// constraint, partial and expression indexes do not count.
+func updateUniqueColumns(table *model.Table) {
+	for _, column := range table.Columns {
+		column.Unique = false
+	}
+	for _, uc := range table.UniqueConstraints {
+		if len(uc.Columns) == 1 && uc.Plain() {
+			table.Columns[uc.Columns[0]].Unique = true
+		}
+	}
+}
+
+func (w *Walker) alterTable(stmt model.Statement, table *model.Table, cmd tree.AlterTableCmd) bool {
+	switch c := cmd.(type) {
+	case *tree.AlterTableAddColumn:
+		if _, ok := table.Columns[string(c.ColumnDef.Name)]; ok && c.IfNotExists {
+			return true
+		}
+		return w.addColumn(stmt, table, c.ColumnDef)
+	case *tree.AlterTableAddConstraint:
+		return w.addConstraint(stmt, table, c.ConstraintDef)
+	case *tree.AlterTableDropColumn:
+		if _, ok := table.Columns[string(c.Column)]; !ok && c.IfExists {
+			return true
+		}
+		return w.dropColumn(stmt, table, c)
+	case *tree.AlterTableRenameColumn:
+		return w.renameColumn(stmt, table, c)
+	case *tree.AlterTableAlterColumnType:
+		column, ok := w.findColumn(stmt, table, c.Column)
+		if !ok {
+			return false
+		}
+		if column.GenerationType != nil && column.Type.Family() != c.ToType.Family() {
+			w.addWarning(stmt, model.CodeUnsupported, stmt.Find(column.Name),
+				fmt.Sprintf("generator of column %s is dropped, it was set for type %s", column.Name, column.Type.SQLStandardName()))
+			column.GenerationType = nil
+		}
+		column.Type = c.ToType
+	case *tree.AlterTableSetNotNull:
+		column, ok := w.findColumn(stmt, table, c.Column)
+		if !ok {
+			return false
+		}
+		column.NotNull = true
+		column.NullRatio = 0
+	case *tree.AlterTableDropNotNull:
+		column, ok := w.findColumn(stmt, table, c.Column)
+		if !ok {
+			return false
+		}
+		column.NotNull = false
+	case *tree.AlterTableSetDefault:
+		// defaults do not change generation, values are always generated
+		if _, ok := w.findColumn(stmt, table, c.Column); !ok {
+			return false
+		}
+	case *tree.AlterTableDropConstraint:
+		if !dropConstraint(table, string(c.Constraint)) && !c.IfExists {
+			w.addWarning(stmt, model.CodeNotFound, stmt.Find(string(c.Constraint)),
+				fmt.Sprintf("constraint %s of table %s.%s not found, it may be a check constraint", c.Constraint, table.Schema, table.Name))
+		}
+	case *tree.AlterTableRenameConstraint:
+		if !renameConstraint(table, string(c.Constraint), string(c.NewName)) {
+			w.addWarning(stmt, model.CodeNotFound, stmt.Find(string(c.Constraint)),
+				fmt.Sprintf("constraint %s of table %s.%s not found, it may be a check constraint", c.Constraint, table.Schema, table.Name))
+		}
+	case *tree.AlterTableAlterPrimaryKey:
+		table.PrimaryKey = make([]string, 0, len(c.Columns))
+		for _, column := range c.Columns {
+			table.PrimaryKey = append(table.PrimaryKey, string(column.Column))
+		}
+		table.PrimaryKeyName = model.ConstraintName(table.Name, nil, "pkey")
+	default:
+		w.addWarning(stmt, model.CodeUnsupported, 0, fmt.Sprintf("%q is not supported and ignored", tree.AsString(cmd)))
+	}
+
+	return true
+}
+
+func (w *Walker) findColumn(stmt model.Statement, table *model.Table, name tree.Name) (*model.Column, bool) {
+	column, ok := table.Columns[string(name)]
+	if !ok {
+		w.addError(stmt, model.CodeNotFound, stmt.Find(string(name)), fmt.Errorf("column %s of table %s.%s not found", string(name), table.Schema, table.Name))
+	}
+
+	return column, ok
+}
+
+// dropColumn removes the column with all constraints and rules that use it.
+// Foreign keys of other tables referencing the column are dropped only with
+// CASCADE, as PostgreSQL does.
+func (w *Walker) dropColumn(stmt model.Statement, table *model.Table, c *tree.AlterTableDropColumn) bool {
+	name := string(c.Column)
+	if _, ok := w.findColumn(stmt, table, c.Column); !ok {
+		return false
+	}
+
+	for _, t := range w.tables() {
+		if t == table {
+			continue
+		}
+		// dropping changes the slice, so iterate over a copy
+		for _, fk := range append([]*model.ForeignKeyConstraint(nil), t.ForeignKeyConstraints...) {
+			if fk.Ref.Table != table || !contains(refColumns(fk), name) {
+				continue
+			}
+			if c.DropBehavior != tree.DropCascade {
+				w.addError(stmt, model.CodeForeignKey, stmt.Find(name), fmt.Errorf("cannot drop column %s of table %s.%s, foreign key %s of table %s.%s references it, use CASCADE",
+					name, table.Schema, table.Name, fk.Name, t.Schema, t.Name))
+				return false
+			}
+			dropConstraint(t, fk.Name)
+		}
+	}
+
+	if contains(table.PrimaryKey, name) {
+		table.PrimaryKey, table.PrimaryKeyName = nil, ""
+	}
+	ucs := table.UniqueConstraints[:0]
+	for _, uc := range table.UniqueConstraints {
+		if !uc.Uses(name) {
+			ucs = append(ucs, uc)
+		}
+	}
+	table.UniqueConstraints = ucs
+	fks := table.ForeignKeyConstraints[:0]
+	for _, fk := range table.ForeignKeyConstraints {
+		if !contains(fk.Columns, name) && !(fk.Ref.Table == table && contains(refColumns(fk), name)) {
+			fks = append(fks, fk)
+		}
+	}
+	table.ForeignKeyConstraints = fks
+	if settings := table.TableGenerationSettings; settings != nil {
+		rules := settings.Rules[:0]
+		for _, rule := range settings.Rules {
+			if rule.Left != name && rule.Right != name {
+				rules = append(rules, rule)
+			}
+		}
+		settings.Rules = rules
+	}
+
+	delete(table.Columns, name)
+	updateUniqueColumns(table)
+
+	return true
+}
+
+func (w *Walker) renameColumn(stmt model.Statement, table *model.Table, c *tree.AlterTableRenameColumn) bool {
+	name, newName := string(c.Column), string(c.NewName)
+	column, ok := w.findColumn(stmt, table, c.Column)
+	if !ok {
+		return false
+	}
+	if _, ok := table.Columns[newName]; ok {
+		w.addError(stmt, model.CodeDuplicate, stmt.Find(newName), fmt.Errorf("column %s already declared", newName))
+		return false
+	}
+
+	delete(table.Columns, name)
+	column.Name = newName
+	table.Columns[newName] = column
+
+	rename(table.PrimaryKey, name, newName)
+	for _, uc := range table.UniqueConstraints {
+		uc.RenameColumn(name, newName)
+	}
+	for _, fk := range table.ForeignKeyConstraints {
+		rename(fk.Columns, name, newName)
+	}
+	for _, t := range w.tables() {
+		for _, fk := range t.ForeignKeyConstraints {
+			if fk.Ref.Table == table {
+				rename(fk.Ref.Columns, name, newName)
+			}
+		}
+	}
+	if settings := table.TableGenerationSettings; settings != nil {
+		for _, rule := range settings.Rules {
+			if rule.Left == name {
+				rule.Left = newName
+			}
+			if rule.Right == name {
+				rule.Right = newName
+			}
+		}
+	}
+
+	return true
+}
+
+func (w *Walker) renameTable(stmt model.Statement, table *model.Table, newName *tree.UnresolvedObjectName) bool {
+	tableName := newName.ToTableName()
+	if s := tableName.Schema(); s != "" && s != table.Schema {
+		w.addError(stmt, model.CodeUnsupported, stmt.Find(s), fmt.Errorf("table %s.%s cannot be moved to schema %s by renaming", table.Schema, table.Name, s))
+		return false
+	}
+
+	schema := w.Schemas[table.Schema]
+	if _, ok := schema.Tables[tableName.Table()]; ok {
+		w.addError(stmt, model.CodeDuplicate, stmt.Find(tableName.Table()), fmt.Errorf("table %s.%s already declared", table.Schema, tableName.Table()))
+		return false
+	}
+
+	delete(schema.Tables, table.Name)
+	table.Name = tableName.Table()
+	schema.Tables[table.Name] = table
+	w.linkPendingRefs(table)
+	for _, t := range w.tables() {
+		for _, fk := range t.ForeignKeyConstraints {
+			if fk.Ref.Table == table {
+				fk.Ref.TableName = table.Name
+			}
+		}
+	}
+
+	return true
+}
+
+// dropTable removes the table. Foreign keys of other tables referencing it
+// are dropped only with CASCADE.
+func (w *Walker) dropTable(stmt model.Statement, table *model.Table, cascade bool) bool {
+	for _, t := range w.tables() {
+		if t == table {
+			continue
+		}
+		for _, fk := range append([]*model.ForeignKeyConstraint(nil), t.ForeignKeyConstraints...) {
+			if fk.Ref.Table != table {
+				continue
+			}
+			if !cascade {
+				w.addError(stmt, model.CodeForeignKey, stmt.Find(table.Name), fmt.Errorf("cannot drop table %s.%s, foreign key %s of table %s.%s references it, use CASCADE",
+					table.Schema, table.Name, fk.Name, t.Schema, t.Name))
+				return false
+			}
+			dropConstraint(t, fk.Name)
+		}
+	}
+
+	delete(w.Schemas[table.Schema].Tables, table.Name)
+	return true
+}
+
+// dropConstraint removes the constraint by name and reports whether it was found.
+func dropConstraint(table *model.Table, name string) bool {
+	if table.PrimaryKeyName == name && len(table.PrimaryKey) > 0 {
+		table.PrimaryKey, table.PrimaryKeyName = nil, ""
+		return true
+	}
+	for i, uc := range table.UniqueConstraints {
+		if uc.Name == name {
+			table.UniqueConstraints = append(table.UniqueConstraints[:i], table.UniqueConstraints[i+1:]...)
+			updateUniqueColumns(table)
+			return true
+		}
+	}
+	for i, fk := range table.ForeignKeyConstraints {
+		if fk.Name == name {
+			table.ForeignKeyConstraints = append(table.ForeignKeyConstraints[:i], table.ForeignKeyConstraints[i+1:]...)
+			return true
+		}
+	}
+
+	return false
+}
+
+func renameConstraint(table *model.Table, name, newName string) bool {
+	if table.PrimaryKeyName == name && len(table.PrimaryKey) > 0 {
+		table.PrimaryKeyName = newName
+		return true
+	}
+	for _, uc := range table.UniqueConstraints {
+		if uc.Name == name {
+			uc.Name = newName
+			return true
+		}
+	}
+	for _, fk := range table.ForeignKeyConstraints {
+		if fk.Name == name {
+			fk.Name = newName
+			return true
+		}
+	}
+
+	return false
+}
+
+// refColumns returns the referenced columns of the foreign key, which are
+// the primary key of the referenced table if they are omitted.
+func refColumns(fk *model.ForeignKeyConstraint) []string {
+	if len(fk.Ref.Columns) == 0 && fk.Ref.Table != nil {
+		return fk.Ref.Table.PrimaryKey
+	}
+
+	return fk.Ref.Columns
+}
+
+// tables returns all tables of all schemas.
+func (w *Walker) tables() []*model.Table {
+	var res []*model.Table
+	for name, schema := range w.Schemas {
+		if name == "" {
+			continue
+		}
+		for _, table := range schema.Tables {
+			res = append(res, table)
+		}
+	}
+
+	return res
+}
+
+func (w *Walker) tableExists(name *tree.UnresolvedObjectName) bool {
+	tableName := name.ToTableName()
+	_, err := w.lookupTable(tableName.Schema(), tableName.Table(), w.searchPath)
+	return err == nil
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+
+	return false
+}
+
+func rename(list []string, name, newName string) {
+	for i, item := range list {
+		if item == name {
+			list[i] = newName
+		}
+	}
+}
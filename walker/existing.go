@@ -0,0 +1,81 @@
+package walker
+
+import (
+	"context"
+	"fmt"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/levtul/tmp/model"
+	"strings"
+)
+
+// keyColumns returns the columns of the table whose values are kept for
+// existing and generated rows: columns of its unique keys and columns
+// referenced by foreign keys of the tables.
+func keyColumns(table *model.Table, tables []*model.Table) []string {
+	var res []string
+	add := func(names ...string) {
+		for _, name := range names {
+			if !contains(res, name) {
+				res = append(res, name)
+			}
+		}
+	}
+
+	for _, key := range table.Keys() {
+		add(key.Columns...)
+		if key.Where != nil {
+			add(key.Where.Columns()...)
+		}
+	}
+	for _, t := range tables {
+		for _, fk := range t.ForeignKeyConstraints {
+			if fk.Ref.Table == table {
+				add(fk.Ref.Columns...)
+			}
+		}
+	}
+
+	return res
+}
+
+// loadExisting reads the columns of all rows of the table and returns
+// their values by column and the number of rows.
+func loadExisting(ctx context.Context, db *pgxpool.Pool, table *model.Table, columns []string) (map[string][]interface{}, int, error) {
+	name := pgx.Identifier{table.Schema, table.Name}.Sanitize()
+	data := map[string][]interface{}{}
+	if len(columns) == 0 {
+		count := 0
+		if err := db.QueryRow(ctx, "SELECT count(*) FROM "+name).Scan(&count); err != nil {
+			return nil, 0, fmt.Errorf("unable to count rows of table %s: %w", name, err)
+		}
+		return data, count, nil
+	}
+
+	quoted := make([]string, 0, len(columns))
+	for _, column := range columns {
+		quoted = append(quoted, pgx.Identifier{column}.Sanitize())
+	}
+	rows, err := db.Query(ctx, fmt.Sprintf("SELECT %s FROM %s", strings.Join(quoted, ", "), name))
+	if err != nil {
+		return nil, 0, fmt.Errorf("unable to read rows of table %s: %w", name, err)
+	}
+	defer rows.Close()
+
+	count := 0
+	for rows.Next() {
+		values, err := rows.Values()
+		if err != nil {
+			return nil, 0, fmt.Errorf("unable to read rows of table %s: %w", name, err)
+		}
+		for i, column := range columns {
+			data[column] = append(data[column], values[i])
+		}
+		count++
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("unable to read rows of table %s: %w", name, err)
+	}
+
+	return data, count, nil
+}
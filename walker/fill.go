@@ -2,14 +2,22 @@ package walker
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	sq "github.com/Masterminds/squirrel"
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
 	"github.com/jackc/pgx/v4/pgxpool"
 	"github.com/levtul/tmp/model"
-	"math/rand"
+	"strings"
+	"sync"
 )
 
-const maxTriesCount = 10
+const (
+	maxTriesCount = 10
+	// maxTableTriesCount is how many times a table rejected by the database
+	// is generated in the savepoint mode.
+	maxTableTriesCount = 3
+)
 
 func (w *Walker) GetTablesOrder() ([]*model.Table, error) {
 	been := map[*model.Table]int{}
@@ -35,6 +43,9 @@ func (w *Walker) GetTablesOrder() ([]*model.Table, error) {
 					prevT = t
 
 					for _, fk := range t.ForeignKeyConstraints {
+						if fk.Ref.Table == nil {
+							return nil, fmt.Errorf("foreign key of table %s.%s references unresolved table %s", t.Schema, t.Name, fk.Ref.TableName)
+						}
 						if been[fk.Ref.Table] == 1 {
 							cycle := make([]*model.Table, 0, len(w.Schemas))
 							for cur := t; (cur != t || len(cycle) == 0) && cur != nil; cur = prev[cur] {
@@ -61,127 +72,379 @@ func (w *Walker) GetTablesOrder() ([]*model.Table, error) {
 	return order, nil
 }
 
-func (w *Walker) FillAllDB(db *pgxpool.Pool) error {
+const (
+	// CleanTruncate empties the tables by one TRUNCATE ... RESTART IDENTITY.
+	CleanTruncate = "truncate"
+	// CleanDelete deletes rows of the tables, children before parents.
+	CleanDelete = "delete"
+)
+
+const (
+	// TxTable commits each table in its own transaction.
+	TxTable = "table"
+	// TxSingle fills all tables in one transaction, a failure leaves the
+	// database untouched.
+	TxSingle = "single"
+	// TxSavepoint is TxSingle with a savepoint per table, a table rejected
+	// by the database is rolled back to its savepoint and generated again.
+	TxSavepoint = "savepoint"
+)
+
+// FillOptions changes how FillAllDB fills the database.
+type FillOptions struct {
+	// Append keeps the rows in the database: their key values are read to
+	// reference them by foreign keys and to keep new rows unique.
+	Append bool
+	// Clean is CleanTruncate or CleanDelete to empty the tables before
+	// filling, in the same transaction as the fill.
+	Clean string
+	// Transaction is TxTable (if empty), TxSingle or TxSavepoint. Cleaning
+	// runs in one transaction with the fill, so it cannot be used with
+	// TxTable.
+	Transaction string
+	// Jobs is the number of tables filled at once, tables of one level of
+	// the dependency graph are independent. The TxSavepoint mode fills one
+	// table at a time and does not allow more jobs.
+	Jobs int
+	// Deferred runs SET CONSTRAINTS ALL DEFERRED, so deferrable constraints
+	// are checked on commit.
+	Deferred bool
+	// Progress, if set, receives the statistics of a table each time a batch
+	// of its rows is generated or inserted. Calls are not concurrent.
+	Progress func(stats TableStats)
+}
+
+// FillAllDB fills the tables and returns their statistics in fill order.
+func (w *Walker) FillAllDB(ctx context.Context, db *pgxpool.Pool, options FillOptions) ([]TableStats, error) {
+	if options.Transaction == "" {
+		options.Transaction = TxTable
+	}
+	if options.Transaction == TxTable && options.Clean != "" {
+		return nil, fmt.Errorf("tables cannot be cleaned in transaction mode %s, cleaning runs in one transaction with the fill", TxTable)
+	}
+	if options.Transaction == TxSavepoint && options.Jobs > 1 {
+		return nil, fmt.Errorf("transaction mode %s fills one table at a time, %d jobs requested", TxSavepoint, options.Jobs)
+	}
+
 	order, err := w.GetTablesOrder()
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	for _, table := range order {
+		if table.TableGenerationSettings == nil {
+			table.TableGenerationSettings = &model.TableGenerationSettings{RowsCount: model.DefaultRowsCount}
+		}
 	}
 
 	generatedData := map[*model.Table]map[string][]interface{}{}
+	existing := map[*model.Table]int{}
+	if options.Append {
+		for _, table := range order {
+			data, count, err := loadExisting(ctx, db, table, keyColumns(table, order))
+			if err != nil {
+				return nil, err
+			}
+			generatedData[table], existing[table] = data, count
+		}
+	}
+
+	if err := planJunctionRows(order, existing); err != nil {
+		return nil, err
+	}
+	if err := checkUniqueDomains(order, existing); err != nil {
+		return nil, err
+	}
+	if err := checkForeignKeySettings(order); err != nil {
+		return nil, err
+	}
+
+	stats := newTracker(order, options.Progress)
+	err = w.fillTables(ctx, db, order, options, generatedData, existing, stats)
+
+	return stats.list(order), err
+}
+
+// fillTables fills the tables level by level in the transaction mode of
+// the options.
+func (w *Walker) fillTables(ctx context.Context, db *pgxpool.Pool, order []*model.Table, options FillOptions, generatedData map[*model.Table]map[string][]interface{}, existing map[*model.Table]int, stats *tracker) error {
+	deferred := func(tx pgx.Tx) error {
+		if !options.Deferred {
+			return nil
+		}
+		if _, err := tx.Exec(ctx, "SET CONSTRAINTS ALL DEFERRED"); err != nil {
+			return fmt.Errorf("unable to defer constraints: %w", err)
+		}
+		return nil
+	}
+
+	jobs := options.Jobs
+	if jobs < 1 {
+		jobs = 1
+	}
+	keep := make(map[*model.Table][]string, len(order))
 	for _, table := range order {
-		if err := w.fillDB(table, db, generatedData); err != nil {
+		keep[table] = keyColumns(table, order)
+	}
+	levels := tableLevels(order)
+
+	if options.Transaction == TxTable {
+		for _, level := range levels {
+			err := fillLevel(level, jobs, generatedData, func(table *model.Table) (kept map[string][]interface{}, err error) {
+				err = inTx(ctx, db, func(tx pgx.Tx) error {
+					if err := deferred(tx); err != nil {
+						return err
+					}
+					kept, err = w.fillTable(ctx, table, generatedData, existing[table], keep[table], execIn(ctx, tx, nil), stats)
+					return err
+				})
+				return kept, err
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	return inTx(ctx, db, func(tx pgx.Tx) error {
+		if err := deferred(tx); err != nil {
 			return err
 		}
+		if options.Clean != "" {
+			if err := clean(ctx, tx, order, options.Clean); err != nil {
+				return err
+			}
+		}
+		// a transaction is not safe for concurrent use, batches of the
+		// tables filled at once are executed one at a time
+		var mu sync.Mutex
+		fill := func(table *model.Table) (map[string][]interface{}, error) {
+			return w.fillTable(ctx, table, generatedData, existing[table], keep[table], execIn(ctx, tx, &mu), stats)
+		}
+		if options.Transaction == TxSavepoint {
+			fill = func(table *model.Table) (map[string][]interface{}, error) {
+				return w.fillWithSavepoint(ctx, tx, table, generatedData, existing[table], keep[table], stats)
+			}
+		}
+		for _, level := range levels {
+			if err := fillLevel(level, jobs, generatedData, fill); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// execIn returns a function executing queries in the transaction, under
+// the mutex if it is not nil.
+func execIn(ctx context.Context, tx pgx.Tx, mu *sync.Mutex) func(sql string, values []interface{}) error {
+	return func(sql string, values []interface{}) error {
+		if mu != nil {
+			mu.Lock()
+			defer mu.Unlock()
+		}
+		_, err := tx.Exec(ctx, sql, values...)
+		return err
+	}
+}
+
+// fillWithSavepoint fills the table under a savepoint. If the database
+// rejects the rows, e.g. by a constraint the generator does not know about,
+// the savepoint is rolled back and the rows are generated again.
+func (w *Walker) fillWithSavepoint(ctx context.Context, tx pgx.Tx, table *model.Table, data map[*model.Table]map[string][]interface{}, existing int, keep []string, stats *tracker) (map[string][]interface{}, error) {
+	var (
+		kept map[string][]interface{}
+		err  error
+	)
+	for try := 0; try < maxTableTriesCount; try++ {
+		err = inTx(ctx, tx, func(savepoint pgx.Tx) error {
+			kept, err = w.fillTable(ctx, table, data, existing, keep, execIn(ctx, savepoint, nil), stats)
+			return err
+		})
+		var pgErr *pgconn.PgError
+		if !errors.As(err, &pgErr) || ctx.Err() != nil {
+			return kept, err
+		}
+	}
+
+	return nil, fmt.Errorf("table %s: %w", table.Name, err)
+}
+
+// beginner is a pool or a transaction, whose Begin starts a savepoint.
+type beginner interface {
+	Begin(ctx context.Context) (pgx.Tx, error)
+}
+
+// inTx runs fn in a transaction, which is committed if fn succeeds.
+func inTx(ctx context.Context, db beginner, fn func(tx pgx.Tx) error) error {
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to begin transaction: %w", err)
+	}
+
+	if err := fn(tx); err != nil {
+		// the transaction is rolled back even if ctx is done
+		if errR := tx.Rollback(context.Background()); errR != nil {
+			return fmt.Errorf("%w; unable to rollback transaction: %v", err, errR)
+		}
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("unable to commit transaction: %w", err)
 	}
 
 	return nil
 }
 
-func (w *Walker) fillDB(table *model.Table, db *pgxpool.Pool, data map[*model.Table]map[string][]interface{}) error {
-	stmt := sq.Insert(table.Name)
-	columns := make([]*model.Column, 0, len(table.Columns))
-	for _, column := range table.Columns {
-		columns = append(columns, column)
-		stmt = stmt.Columns(column.Name)
-	}
-
-	fks := table.ForeignKeyConstraints
-	ucs := table.UniqueConstraints
-	ucs = append(ucs, &table.PrimaryKey)
-
-	if table.TableGenerationSettings == nil {
-		table.TableGenerationSettings = &model.TableGenerationSettings{RowsCount: 100}
-	}
-	prevValues := make([]map[string]interface{}, 0, table.TableGenerationSettings.RowsCount)
-	for i := 0; i < table.TableGenerationSettings.RowsCount; i++ {
-		generated := false
-		for try := 0; try < maxTriesCount; try++ {
-			rowMap := make(map[string]interface{}, len(table.Columns))
-			for _, fk := range fks {
-				cnt := fk.Ref.Table.TableGenerationSettings.RowsCount
-				if cnt == 0 {
-					return fmt.Errorf("table %s has no rows", fk.Ref.Table.Name)
-				}
+// clean empties the tables, order is the fill order of the tables.
+func clean(ctx context.Context, tx pgx.Tx, order []*model.Table, mode string) error {
+	names := make([]string, 0, len(order))
+	for i := len(order) - 1; i >= 0; i-- {
+		names = append(names, pgx.Identifier{order[i].Schema, order[i].Name}.Sanitize())
+	}
+	if len(names) == 0 {
+		return nil
+	}
 
-				rowN := rand.Intn(cnt)
-				for i, column := range fk.Columns {
-					rowMap[column] = data[fk.Ref.Table][fk.Ref.Columns[i]][rowN]
-				}
+	switch mode {
+	case CleanTruncate:
+		// no CASCADE: it would also empty tables outside of the schema that
+		// reference these ones, without it PostgreSQL reports them instead
+		if _, err := tx.Exec(ctx, "TRUNCATE "+strings.Join(names, ", ")+" RESTART IDENTITY"); err != nil {
+			return fmt.Errorf("unable to truncate tables: %w", err)
+		}
+	case CleanDelete:
+		for _, name := range names {
+			if _, err := tx.Exec(ctx, "DELETE FROM "+name); err != nil {
+				return fmt.Errorf("unable to delete rows of table %s: %w", name, err)
 			}
+		}
+	default:
+		return fmt.Errorf("unknown clean mode: %s", mode)
+	}
 
-			row := make([]interface{}, 0, len(table.Columns))
-			for _, column := range columns {
-				if _, ok := rowMap[column.Name]; !ok {
-					rowMap[column.Name] = column.GenerateValue()
-				}
+	return nil
+}
 
-				row = append(row, rowMap[column.Name])
+// checkUniqueDomains reports unique constraints whose columns cannot take as
+// many distinct values as rows are requested, together with the existing
+// rows. Unique keys made of foreign keys are checked against the row counts of
+// the referenced tables, other constraints with foreign key or nullable
+// columns are not checked.
+func checkUniqueDomains(order []*model.Table, existing map[*model.Table]int) error {
+	for _, table := range order {
+		rows := int64(existing[table] + table.TableGenerationSettings.RowsCount)
+		fkColumns := table.ForeignKeyColumns()
+		for _, uc := range table.Keys() {
+			if !uc.Plain() {
+				continue
 			}
 
-			valid := true
-			for _, uc := range ucs {
-				for _, col := range prevValues {
-					foundNonEqual := len(prevValues) == 0
-					for _, column := range *uc {
-						if col[column] != rowMap[column] {
-							foundNonEqual = true
-							break
-						}
-					}
-					if !foundNonEqual {
-						valid = false
-						break
-					}
+			size, finite := int64(1), true
+			for _, name := range uc.Columns {
+				column := table.Columns[name]
+				if fkColumns[name] || column.NullRatio > 0 {
+					finite = false
+					break
 				}
-
-				if !valid {
+				generator := model.NewUniqueGenerator(column)
+				if generator == nil {
+					finite = false
+					break
+				}
+				if size *= generator.DomainSize(); size >= rows {
 					break
 				}
-			}
-			if !valid {
-				continue
 			}
 
-			stmt = stmt.Values(row...)
-			prevValues = append(prevValues, rowMap)
-			generated = true
-			break
+			if finite && size < rows {
+				return fmt.Errorf("table %s.%s: columns (%s) of unique constraint %s have %d distinct values, %d rows requested",
+					table.Schema, table.Name, strings.Join(uc.Columns, ", "), uc.Name, size, rows)
+			}
 		}
 
-		if !generated {
-			return fmt.Errorf("unable to generate unique row for table %s", table.Name)
+		for _, group := range uniqueForeignKeyGroups(table) {
+			size := int64(1)
+			for _, fk := range group.fks {
+				if size *= int64(existing[fk.Ref.Table] + fk.Ref.Table.TableGenerationSettings.RowsCount); size >= rows {
+					break
+				}
+			}
+			if size < rows {
+				return fmt.Errorf("table %s.%s: foreign keys %s make up a unique key and have %d combinations of referenced rows, %d rows requested",
+					table.Schema, table.Name, foreignKeyNames(group.fks), size, rows)
+			}
 		}
 	}
 
-	//stmt.Suffix("ON CONFLICT DO NOTHING")
-	sql, values, err := stmt.PlaceholderFormat(sq.Dollar).ToSql()
-	if err != nil {
-		return err
-	}
+	return nil
+}
 
-	tx, err := db.Begin(context.Background())
-	if err != nil {
-		return fmt.Errorf("unable to begin transaction: %w", err)
-	}
-	_, err = tx.Exec(context.Background(), sql, values...)
-	if err != nil {
-		errR := tx.Rollback(context.Background())
-		if errR != nil {
-			return fmt.Errorf("unable to rollback transaction: %w", errR)
+// checkForeignKeySettings reports skew set for columns that are not foreign
+// key columns, and skew or null ratio set for foreign keys making up a unique
+// key, whose referenced rows are chosen without repeats.
+func checkForeignKeySettings(order []*model.Table) error {
+	for _, table := range order {
+		fkColumns := table.ForeignKeyColumns()
+		for _, column := range table.Columns {
+			if column.Skew > 0 && !fkColumns[column.Name] {
+				return fmt.Errorf("table %s.%s: column %s: skew is set, but the column is not a foreign key column", table.Schema, table.Name, column.Name)
+			}
+		}
+		for _, group := range uniqueForeignKeyGroups(table) {
+			for _, fk := range group.fks {
+				nullRatio, skew := fk.Settings(table)
+				if skew > 0 || nullRatio > 0 {
+					return fmt.Errorf("table %s.%s: foreign key %s: skew or null ratio is set, but the foreign key is a part of unique key %s and its referenced rows are chosen without repeats",
+						table.Schema, table.Name, fk.Name, group.key.Name)
+				}
+			}
 		}
-		return fmt.Errorf("unable to execute query: %w", err)
 	}
-	err = tx.Commit(context.Background())
-	if err != nil {
-		return fmt.Errorf("unable to commit transaction: %w", err)
+
+	return nil
+}
+
+// newUniqueGenerators returns generators without repeats for columns having
+// a single-column unique constraint, if their values can be enumerated.
+// Foreign key columns take values of referenced rows and are skipped.
+func newUniqueGenerators(table *model.Table, keys []*model.UniqueConstraint) map[string]*model.UniqueGenerator {
+	res := map[string]*model.UniqueGenerator{}
+	fkColumns := table.ForeignKeyColumns()
+	for _, uc := range keys {
+		if len(uc.Columns) != 1 || !uc.Plain() || fkColumns[uc.Columns[0]] {
+			continue
+		}
+		if generator := model.NewUniqueGenerator(table.Columns[uc.Columns[0]]); generator != nil {
+			res[uc.Columns[0]] = generator
+		}
 	}
 
-	data[table] = map[string][]interface{}{}
-	for _, value := range prevValues {
-		for column, val := range value {
-			data[table][column] = append(data[table][column], val)
+	return res
+}
+
+// enforceRules adjusts the row until all table rules hold. Fixing one rule
+// may break another one sharing a column, so rules are revisited a bounded
+// number of times.
+func enforceRules(table *model.Table, row map[string]interface{}, fixed map[string]bool) bool {
+	rules := table.TableGenerationSettings.Rules
+	for pass := 0; pass <= len(rules); pass++ {
+		valid := true
+		for _, rule := range rules {
+			if !rule.Holds(row, table) {
+				valid = false
+				if !rule.Enforce(row, fixed, table) {
+					return false
+				}
+			}
+		}
+
+		if valid {
+			return true
 		}
 	}
 
-	return nil
+	return false
 }
@@ -0,0 +1,122 @@
+package walker
+
+import (
+	"fmt"
+	"github.com/auxten/postgresql-parser/pkg/sql/sem/tree"
+	"github.com/levtul/tmp/model"
+)
+
+// CreateIndex adds a unique index to the unique constraints of its table,
+// other indexes do not restrict generated data. The statement is not walked
+// because the SQL parser does not support expression and partial indexes.
+func (w *Walker) CreateIndex(stmt model.Statement) {
+	def, err := model.ParseCreateIndex(stmt.Text)
+	if err != nil {
+		w.addError(stmt, model.CodeSyntax, 0, err)
+		return
+	}
+	if !def.Unique {
+		return
+	}
+
+	table, err := w.lookupTable(def.Schema, def.Table, w.searchPath)
+	if err != nil {
+		w.addError(stmt, model.CodeNotFound, stmt.Find(def.Table), err)
+		return
+	}
+	for _, uc := range table.UniqueConstraints {
+		if def.Name != "" && uc.Name == def.Name {
+			if !def.IfNotExists {
+				w.addError(stmt, model.CodeDuplicate, stmt.Find(def.Name), fmt.Errorf("index %s already declared", def.Name))
+			}
+			return
+		}
+	}
+
+	uc := &model.UniqueConstraint{Name: def.Name, NullsNotDistinct: def.NullsNotDistinct}
+	var names []string
+	plain := true
+	for _, element := range def.Elements {
+		if element.Expr == "" {
+			if _, ok := w.findColumn(stmt, table, tree.Name(element.Column)); !ok {
+				return
+			}
+			uc.Columns = append(uc.Columns, element.Column)
+			uc.Exprs = append(uc.Exprs, model.IndexColumn(element.Column))
+			names = append(names, element.Column)
+			continue
+		}
+
+		expr, err := model.NewIndexExpr(element.Expr)
+		if err != nil {
+			w.addWarning(stmt, model.CodeUnsupported, element.Offset, fmt.Sprintf("unique index on %s is ignored: %s", element.Expr, err))
+			return
+		}
+		if !w.checkIndexColumns(stmt, table, expr) {
+			return
+		}
+		plain = false
+		uc.Columns = append(uc.Columns, expr.Columns()...)
+		uc.Exprs = append(uc.Exprs, expr)
+		names = append(names, "expr")
+	}
+	if plain {
+		uc.Exprs = nil
+	}
+
+	if def.Where != "" {
+		where, err := model.NewIndexExpr(def.Where)
+		if err != nil {
+			w.addWarning(stmt, model.CodeUnsupported, def.WhereOffset, fmt.Sprintf("predicate of unique index is ignored, uniqueness is checked on all rows: %s", err))
+		} else if !w.checkIndexColumns(stmt, table, where) {
+			return
+		} else {
+			uc.Where = where
+		}
+	}
+
+	if uc.Name == "" {
+		uc.Name = model.ConstraintName(table.Name, names, "idx")
+	}
+	table.UniqueConstraints = append(table.UniqueConstraints, uc)
+	updateUniqueColumns(table)
+}
+
+func (w *Walker) checkIndexColumns(stmt model.Statement, table *model.Table, expr *model.IndexExpr) bool {
+	for _, name := range expr.Columns() {
+		if _, ok := w.findColumn(stmt, table, tree.Name(name)); !ok {
+			return false
+		}
+	}
+
+	return true
+}
+
+// dropIndex removes unique indexes by name, indexes without a schema are
+// looked up in the search path. Other indexes are not tracked, so unknown
+// names are ignored.
+func (w *Walker) dropIndex(n *tree.DropIndex) {
+next:
+	for _, index := range n.IndexList {
+		schemas := w.searchPath
+		if index.Table.ExplicitSchema {
+			schemas = []string{index.Table.Schema()}
+		}
+
+		for _, s := range schemas {
+			schema, ok := w.Schemas[s]
+			if !ok {
+				continue
+			}
+			for _, table := range schema.Tables {
+				for i, uc := range table.UniqueConstraints {
+					if uc.Name == string(index.Index) {
+						table.UniqueConstraints = append(table.UniqueConstraints[:i], table.UniqueConstraints[i+1:]...)
+						updateUniqueColumns(table)
+						continue next
+					}
+				}
+			}
+		}
+	}
+}
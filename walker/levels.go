@@ -0,0 +1,85 @@
+package walker
+
+import (
+	"github.com/levtul/tmp/model"
+	"sync"
+)
+
+// tableLevels groups the tables by their depth in the dependency graph:
+// tables of a level reference only themselves and tables of the previous
+// levels. order is the fill order of the tables.
+func tableLevels(order []*model.Table) [][]*model.Table {
+	depth := make(map[*model.Table]int, len(order))
+	var levels [][]*model.Table
+	for _, table := range order {
+		d := 0
+		for _, fk := range table.ForeignKeyConstraints {
+			if fk.Ref.Table != table && depth[fk.Ref.Table]+1 > d {
+				d = depth[fk.Ref.Table] + 1
+			}
+		}
+		depth[table] = d
+		if d == len(levels) {
+			levels = append(levels, nil)
+		}
+		levels[d] = append(levels[d], table)
+	}
+
+	return levels
+}
+
+// fillLevel fills the tables of a level by jobs goroutines. Filling reads
+// data of the previous levels only, so the kept values of the level are
+// added to data once all of its tables are filled.
+func fillLevel(level []*model.Table, jobs int, data map[*model.Table]map[string][]interface{}, fill func(table *model.Table) (map[string][]interface{}, error)) error {
+	var (
+		mu       sync.Mutex
+		firstErr error
+		wg       sync.WaitGroup
+	)
+	kept := make(map[*model.Table]map[string][]interface{}, len(level))
+
+	tables := make(chan *model.Table, len(level))
+	for _, table := range level {
+		tables <- table
+	}
+	close(tables)
+
+	for i := 0; i < jobs && i < len(level); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for table := range tables {
+				mu.Lock()
+				failed := firstErr != nil
+				mu.Unlock()
+				if failed {
+					continue
+				}
+
+				values, err := fill(table)
+				mu.Lock()
+				if err != nil && firstErr == nil {
+					firstErr = err
+				}
+				kept[table] = values
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return firstErr
+	}
+	for table, values := range kept {
+		if data[table] == nil {
+			data[table] = map[string][]interface{}{}
+		}
+		for column, v := range values {
+			data[table][column] = append(data[table][column], v...)
+		}
+	}
+
+	return nil
+}
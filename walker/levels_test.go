@@ -0,0 +1,41 @@
+package walker
+
+import (
+	"github.com/levtul/tmp/model"
+	"reflect"
+	"testing"
+)
+
+func TestTableLevels(t *testing.T) {
+	tables := map[string]*model.Table{}
+	for _, name := range []string{"a", "b", "c", "d", "e", "f"} {
+		tables[name] = &model.Table{Schema: "public", Name: name}
+	}
+	reference := func(from string, to ...string) {
+		for _, name := range to {
+			tables[from].ForeignKeyConstraints = append(tables[from].ForeignKeyConstraints,
+				&model.ForeignKeyConstraint{Ref: &model.ForeignKeyRef{Table: tables[name]}})
+		}
+	}
+	// a diamond a <- b, c <- d, e references a and itself, f only itself
+	reference("b", "a")
+	reference("c", "a")
+	reference("d", "b", "c")
+	reference("e", "e", "a")
+	reference("f", "f")
+
+	order := []*model.Table{tables["a"], tables["b"], tables["c"], tables["d"], tables["e"], tables["f"]}
+	var got [][]string
+	for _, level := range tableLevels(order) {
+		var names []string
+		for _, table := range level {
+			names = append(names, table.Name)
+		}
+		got = append(got, names)
+	}
+
+	want := [][]string{{"a", "f"}, {"b", "c", "e"}, {"d"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("tableLevels() = %q, expected %q", got, want)
+	}
+}
@@ -0,0 +1,426 @@
+package walker
+
+import (
+	"fmt"
+	"github.com/levtul/tmp/model"
+	"math"
+	"math/rand"
+	"sort"
+	"strings"
+)
+
+// foreignKeyGroup is a set of foreign keys whose columns together make up
+// the unique key of the table.
+type foreignKeyGroup struct {
+	key *model.UniqueConstraint
+	fks []*model.ForeignKeyConstraint
+}
+
+// uniqueForeignKeyGroups returns the foreign keys whose columns together make
+// up a unique key of the table: a single foreign key for one-to-one relations,
+// several ones for junction tables. Each combination of referenced rows of a
+// group may be used only once. A foreign key belongs to at most one group,
+// narrower keys are grouped first.
+func uniqueForeignKeyGroups(table *model.Table) []foreignKeyGroup {
+	keys := table.Keys()
+	for i := 1; i < len(keys); i++ {
+		for j := i; j > 0 && len(keys[j].Columns) < len(keys[j-1].Columns); j-- {
+			keys[j], keys[j-1] = keys[j-1], keys[j]
+		}
+	}
+
+	var res []foreignKeyGroup
+	grouped := map[*model.ForeignKeyConstraint]bool{}
+	for _, key := range keys {
+		if !key.Plain() {
+			continue
+		}
+
+		var group []*model.ForeignKeyConstraint
+		covered := map[string]bool{}
+		for _, fk := range table.ForeignKeyConstraints {
+			if grouped[fk] || !containsAll(key.Columns, fk.Columns) {
+				continue
+			}
+			group = append(group, fk)
+			for _, column := range fk.Columns {
+				covered[column] = true
+			}
+		}
+		if len(group) == 0 || len(covered) != len(key.Columns) {
+			continue
+		}
+
+		// the foreign key of the first key column goes first
+		sort.SliceStable(group, func(i, j int) bool {
+			return columnIndex(key.Columns, group[i].Columns[0]) < columnIndex(key.Columns, group[j].Columns[0])
+		})
+		for _, fk := range group {
+			grouped[fk] = true
+		}
+		res = append(res, foreignKeyGroup{key: key, fks: group})
+	}
+
+	return res
+}
+
+// junctionGroup returns the first group of two or more foreign keys making
+// up a unique key, tables having one are junction tables.
+func junctionGroup(table *model.Table) []*model.ForeignKeyConstraint {
+	for _, group := range uniqueForeignKeyGroups(table) {
+		if len(group.fks) > 1 {
+			return group.fks
+		}
+	}
+
+	return nil
+}
+
+// planJunctionRows sets the number of rows of junction tables with density
+// or fan-out from the numbers of existing and planned rows of the referenced
+// tables, so it is known before any table is filled. For fan-out the number
+// of rows of every referenced row is drawn here, the sampler spreads their
+// total over the referenced rows.
+func planJunctionRows(order []*model.Table, existing map[*model.Table]int) error {
+	plannedRows := func(fk *model.ForeignKeyConstraint) int {
+		return existing[fk.Ref.Table] + fk.Ref.Table.TableGenerationSettings.RowsCount
+	}
+	for _, table := range order {
+		settings := table.TableGenerationSettings
+		if settings.Density == 0 && settings.FanOut == nil {
+			continue
+		}
+
+		group := junctionGroup(table)
+		if group == nil {
+			return fmt.Errorf("table %s.%s: density and fanout are set, but the table is not a junction table with a unique key made of two or more foreign keys",
+				table.Schema, table.Name)
+		}
+
+		if settings.FanOut == nil {
+			size, ok := combinationCount(group, plannedRows)
+			if !ok {
+				return fmt.Errorf("table %s.%s: density is set, but the number of combinations of rows referenced by foreign keys %s overflows",
+					table.Schema, table.Name, foreignKeyNames(group))
+			}
+			settings.RowsCount = int(math.Round(settings.Density * float64(size)))
+			continue
+		}
+
+		others, ok := combinationCount(group[1:], plannedRows)
+		if !ok {
+			others = math.MaxInt64
+		}
+		settings.RowsCount = 0
+		for i := 0; i < plannedRows(group[0]); i++ {
+			n := int64(settings.FanOut.Min + rand.Intn(settings.FanOut.Max-settings.FanOut.Min+1))
+			if n > others {
+				n = others
+			}
+			settings.RowsCount += int(n)
+		}
+	}
+
+	return nil
+}
+
+// combinationCount returns the number of combinations of the rows referenced
+// by the foreign keys, ok is false if it overflows.
+func combinationCount(fks []*model.ForeignKeyConstraint, rows func(fk *model.ForeignKeyConstraint) int) (size int64, ok bool) {
+	size = 1
+	for _, fk := range fks {
+		n := int64(rows(fk))
+		if n > 0 && size > math.MaxInt64/n {
+			return 0, false
+		}
+		size *= n
+	}
+
+	return size, true
+}
+
+func columnIndex(columns []string, name string) int {
+	for i, column := range columns {
+		if column == name {
+			return i
+		}
+	}
+
+	return -1
+}
+
+func containsAll(columns, subset []string) bool {
+	for _, name := range subset {
+		if !contains(columns, name) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func foreignKeyNames(fks []*model.ForeignKeyConstraint) string {
+	names := make([]string, 0, len(fks))
+	for _, fk := range fks {
+		names = append(names, fk.Name)
+	}
+
+	return strings.Join(names, ", ")
+}
+
+// parentRows returns indexes of the generated rows of the referenced table
+// that have no NULL in the referenced columns.
+func parentRows(fk *model.ForeignKeyConstraint, data map[*model.Table]map[string][]interface{}) []int {
+	count := len(data[fk.Ref.Table][fk.Ref.Columns[0]])
+	rows := make([]int, 0, count)
+	for i := 0; i < count; i++ {
+		null := false
+		for _, column := range fk.Ref.Columns {
+			if data[fk.Ref.Table][column][i] == nil {
+				null = true
+				break
+			}
+		}
+		if !null {
+			rows = append(rows, i)
+		}
+	}
+
+	return rows
+}
+
+// referenceSampler chooses referenced rows for the foreign keys of a table.
+// Foreign keys of unique groups take combinations of referenced rows without
+// replacement, the others take random referenced rows, uniformly or by Zipf
+// distribution if skewed, or are NULL with their null ratio.
+type referenceSampler struct {
+	data      map[*model.Table]map[string][]interface{}
+	rows      map[*model.ForeignKeyConstraint][]int
+	nullRatio map[*model.ForeignKeyConstraint]float64
+	zipf      map[*model.ForeignKeyConstraint]*rand.Zipf
+	groups    []foreignKeyGroup
+	grouped   map[*model.ForeignKeyConstraint]bool
+	// combinations of the groups, nil if their number overflows
+	combinations []combinations
+}
+
+// combinations yields indexes of combinations of referenced rows, Peek
+// returns the same index until Take is called.
+type combinations interface {
+	Peek() (int64, bool)
+	Take()
+}
+
+// plannedCombinations yields the combinations chosen for a junction table.
+type plannedCombinations struct {
+	indexes []int64
+	next    int
+}
+
+func (p *plannedCombinations) Peek() (int64, bool) {
+	if p.next >= len(p.indexes) {
+		return 0, false
+	}
+	return p.indexes[p.next], true
+}
+
+func (p *plannedCombinations) Take() {
+	p.next++
+}
+
+func newReferenceSampler(table *model.Table, data map[*model.Table]map[string][]interface{}) (*referenceSampler, error) {
+	s := &referenceSampler{
+		data:      data,
+		rows:      map[*model.ForeignKeyConstraint][]int{},
+		nullRatio: map[*model.ForeignKeyConstraint]float64{},
+		zipf:      map[*model.ForeignKeyConstraint]*rand.Zipf{},
+		grouped:   map[*model.ForeignKeyConstraint]bool{},
+	}
+	for _, fk := range table.ForeignKeyConstraints {
+		nullRatio, skew := fk.Settings(table)
+		s.nullRatio[fk] = nullRatio
+		s.rows[fk] = parentRows(fk, data)
+		if len(s.rows[fk]) == 0 && nullRatio < 1 {
+			return nil, fmt.Errorf("table %s has no rows to reference by foreign key %s of table %s", fk.Ref.Table.Name, fk.Name, table.Name)
+		}
+		if skew > 0 && len(s.rows[fk]) > 0 {
+			// Zipf prefers low ranks, shuffled rows make any parent a frequent one
+			rows := s.rows[fk]
+			rand.Shuffle(len(rows), func(i, j int) {
+				rows[i], rows[j] = rows[j], rows[i]
+			})
+			s.zipf[fk] = rand.NewZipf(rand.New(rand.NewSource(rand.Int63())), skew, 1, uint64(len(s.rows[fk])-1))
+		}
+	}
+
+	settings := table.TableGenerationSettings
+	junction := junctionGroup(table)
+	for _, g := range uniqueForeignKeyGroups(table) {
+		group := g.fks
+		empty := false
+		for _, fk := range group {
+			empty = empty || len(s.rows[fk]) == 0
+		}
+		if empty {
+			// the foreign key with no rows to reference is always NULL and
+			// keys with NULL never conflict, so the group is not sampled
+			continue
+		}
+		s.groups = append(s.groups, g)
+
+		for _, fk := range group {
+			s.grouped[fk] = true
+		}
+		size, ok := combinationCount(group, s.rowCount)
+
+		switch {
+		case len(junction) > 0 && group[0] == junction[0] && settings.FanOut != nil:
+			planned, err := s.planFanOut(group, settings.FanOut, settings.RowsCount)
+			if err != nil {
+				return nil, fmt.Errorf("table %s: %w", table.Name, err)
+			}
+			s.combinations = append(s.combinations, planned)
+			continue
+		case !ok:
+			s.combinations = append(s.combinations, nil)
+			continue
+		}
+
+		if size < int64(settings.RowsCount) {
+			return nil, fmt.Errorf("table %s: foreign keys %s make up a unique key and have %d combinations of referenced rows without NULL, %d rows requested",
+				table.Name, foreignKeyNames(group), size, settings.RowsCount)
+		}
+		s.combinations = append(s.combinations, model.NewSampler(size))
+	}
+
+	return s, nil
+}
+
+// Fill sets the foreign key columns of the row and marks them fixed. It
+// returns false if the combinations of a group are exhausted.
+func (s *referenceSampler) Fill(table *model.Table, row map[string]interface{}, fixed map[string]bool) bool {
+	for _, fk := range table.ForeignKeyConstraints {
+		if s.grouped[fk] {
+			continue
+		}
+
+		if ratio := s.nullRatio[fk]; ratio > 0 && rand.Float64() < ratio {
+			for _, column := range fk.Columns {
+				row[column] = nil
+				fixed[column] = true
+			}
+			continue
+		}
+		rows := s.rows[fk]
+		if zipf, ok := s.zipf[fk]; ok {
+			s.copy(fk, rows[zipf.Uint64()], row, fixed)
+			continue
+		}
+		s.copy(fk, rows[rand.Intn(len(rows))], row, fixed)
+	}
+
+	for i, g := range s.groups {
+		group := g.fks
+		c := s.combinations[i]
+		if c == nil {
+			for _, fk := range group {
+				rows := s.rows[fk]
+				s.copy(fk, rows[rand.Intn(len(rows))], row, fixed)
+			}
+			continue
+		}
+
+		index, ok := c.Peek()
+		if !ok {
+			return false
+		}
+		for j := len(group) - 1; j >= 0; j-- {
+			rows := s.rows[group[j]]
+			s.copy(group[j], rows[index%int64(len(rows))], row, fixed)
+			index /= int64(len(rows))
+		}
+	}
+
+	return true
+}
+
+// Take takes the combinations used by the last Fill.
+func (s *referenceSampler) Take() {
+	for _, c := range s.combinations {
+		if c != nil {
+			c.Take()
+		}
+	}
+}
+
+// Skip takes the combination used by the last Fill for the unique key, if
+// the key is made of foreign keys, and reports whether it did. It is used
+// when the combination is already taken by a row in the database.
+func (s *referenceSampler) Skip(key *model.UniqueConstraint) bool {
+	for i, g := range s.groups {
+		if s.combinations[i] != nil && sameColumns(g.key.Columns, key.Columns) {
+			s.combinations[i].Take()
+			return true
+		}
+	}
+
+	return false
+}
+
+func (s *referenceSampler) rowCount(fk *model.ForeignKeyConstraint) int {
+	return len(s.rows[fk])
+}
+
+// planFanOut chooses total combinations for a junction table so that every
+// row referenced by the first foreign key of the group gets a number of rows
+// within the fan-out, with distinct rows of the other foreign keys.
+func (s *referenceSampler) planFanOut(group []*model.ForeignKeyConstraint, fanOut *model.FanOut, total int) (*plannedCombinations, error) {
+	others, ok := combinationCount(group[1:], s.rowCount)
+	if !ok {
+		others = math.MaxInt64
+	}
+	lo, hi := int64(fanOut.Min), int64(fanOut.Max)
+	if lo > others {
+		lo = others
+	}
+	if hi > others {
+		hi = others
+	}
+
+	parents := int64(len(s.rows[group[0]]))
+	extra := int64(total) - lo*parents
+	if extra < 0 || extra > (hi-lo)*parents {
+		return nil, fmt.Errorf("foreign key %s has %d referenced rows without NULL, fanout cannot make %d rows of them", group[0].Name, parents, total)
+	}
+
+	// each parent gets lo rows and a random share of the extra ones
+	counts := make([]int64, parents)
+	slots := model.NewSampler((hi - lo) * parents)
+	for ; extra > 0; extra-- {
+		slot, _ := slots.Peek()
+		slots.Take()
+		counts[slot/(hi-lo)]++
+	}
+
+	planned := &plannedCombinations{}
+	for i := range s.rows[group[0]] {
+		sampler := model.NewSampler(others)
+		for j := int64(0); j < lo+counts[i]; j++ {
+			other, _ := sampler.Peek()
+			sampler.Take()
+			planned.indexes = append(planned.indexes, int64(i)*others+other)
+		}
+	}
+	rand.Shuffle(len(planned.indexes), func(i, j int) {
+		planned.indexes[i], planned.indexes[j] = planned.indexes[j], planned.indexes[i]
+	})
+
+	return planned, nil
+}
+
+func (s *referenceSampler) copy(fk *model.ForeignKeyConstraint, rowN int, row map[string]interface{}, fixed map[string]bool) {
+	for i, column := range fk.Columns {
+		row[column] = s.data[fk.Ref.Table][fk.Ref.Columns[i]][rowN]
+		fixed[column] = true
+	}
+}
@@ -0,0 +1,250 @@
+package walker
+
+import (
+	"context"
+	"fmt"
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v4"
+	"github.com/levtul/tmp/model"
+	"math/rand"
+	"sync"
+)
+
+const (
+	// batchSize is the number of rows inserted by one query.
+	batchSize = 1000
+	// maxQueryParams is the limit of bind parameters of a PostgreSQL query.
+	maxQueryParams = 65535
+)
+
+// rowGenerator generates rows of a table after the existing rows, whose key
+// values are the first ones in data. It only reads data.
+type rowGenerator struct {
+	table            *model.Table
+	columns          []*model.Column
+	references       *referenceSampler
+	keys             []*model.UniqueConstraint
+	sets             []*model.UniqueSet
+	uniqueGenerators map[string]*model.UniqueGenerator
+	// retries counts rows generated again because of unique keys
+	retries int
+}
+
+func newRowGenerator(table *model.Table, data map[*model.Table]map[string][]interface{}, existing int) (*rowGenerator, error) {
+	g := &rowGenerator{table: table, columns: make([]*model.Column, 0, len(table.Columns))}
+	for _, column := range table.Columns {
+		g.columns = append(g.columns, column)
+	}
+
+	var err error
+	g.references, err = newReferenceSampler(table, data)
+	if err != nil {
+		return nil, err
+	}
+	g.keys = table.Keys()
+	g.sets = make([]*model.UniqueSet, 0, len(g.keys))
+	for _, uc := range g.keys {
+		set := model.NewUniqueSet(table, uc)
+		for i := 0; i < existing; i++ {
+			row := make(map[string]interface{}, len(data[table]))
+			for column, values := range data[table] {
+				row[column] = values[i]
+			}
+			set.Add(row)
+		}
+		g.sets = append(g.sets, set)
+	}
+	g.uniqueGenerators = newUniqueGenerators(table, g.keys)
+
+	return g, nil
+}
+
+// insert returns the insert statement of the table without rows.
+func (g *rowGenerator) insert() sq.InsertBuilder {
+	stmt := sq.Insert(pgx.Identifier{g.table.Schema, g.table.Name}.Sanitize())
+	for _, column := range g.columns {
+		stmt = stmt.Columns(pgx.Identifier{column.Name}.Sanitize())
+	}
+
+	return stmt
+}
+
+// batchSize returns the number of rows of one insert query.
+func (g *rowGenerator) batchSize() int {
+	size := batchSize
+	if len(g.columns) > 0 && size*len(g.columns) > maxQueryParams {
+		size = maxQueryParams / len(g.columns)
+	}
+
+	return size
+}
+
+// Next generates a row that keeps the unique keys unique.
+func (g *rowGenerator) Next() (map[string]interface{}, error) {
+	table := g.table
+	for try := 0; try < maxTriesCount; try++ {
+		rowMap := make(map[string]interface{}, len(table.Columns))
+		fixed := make(map[string]bool, len(table.Columns))
+		if !g.references.Fill(table, rowMap, fixed) {
+			return nil, fmt.Errorf("unable to generate unique row for table %s: combinations of referenced rows are exhausted", table.Name)
+		}
+
+		rowCtx := model.NewRowContext()
+		exhausted := false
+		for _, column := range g.columns {
+			if _, ok := rowMap[column.Name]; ok {
+				continue
+			}
+			if column.NullRatio > 0 && rand.Float64() < column.NullRatio {
+				rowMap[column.Name] = nil
+				continue
+			}
+			generator, ok := g.uniqueGenerators[column.Name]
+			if !ok {
+				rowMap[column.Name] = column.GenerateNotNull(rowCtx)
+				continue
+			}
+			if rowMap[column.Name], ok = generator.Next(); !ok && !column.Nullable(table) {
+				exhausted = true
+			}
+			// rules must not replace a sampled value by a repeated one
+			fixed[column.Name] = true
+		}
+		if exhausted {
+			return nil, fmt.Errorf("unable to generate unique row for table %s: distinct values of unique columns are exhausted", table.Name)
+		}
+
+		if !enforceRules(table, rowMap, fixed) {
+			continue
+		}
+
+		conflict := -1
+		for i, set := range g.sets {
+			if set.Contains(rowMap) {
+				conflict = i
+				break
+			}
+		}
+		if conflict >= 0 {
+			// values taken by existing rows are skipped, not retried
+			key := g.keys[conflict]
+			skipped := g.references.Skip(key)
+			if len(key.Columns) == 1 && rowMap[key.Columns[0]] != nil {
+				if generator, ok := g.uniqueGenerators[key.Columns[0]]; ok {
+					generator.Take()
+					skipped = true
+				}
+			}
+			if skipped {
+				try--
+			} else {
+				g.retries++
+			}
+			continue
+		}
+
+		for _, set := range g.sets {
+			set.Add(rowMap)
+		}
+		g.references.Take()
+		for name, generator := range g.uniqueGenerators {
+			if rowMap[name] != nil {
+				generator.Take()
+			}
+		}
+		return rowMap, nil
+	}
+
+	return nil, fmt.Errorf("unable to generate unique row for table %s", table.Name)
+}
+
+// fillTable generates rows of the table and inserts them by batches through
+// exec, the next batch is generated while the previous one is inserted.
+// Generation stops once ctx is done or an insert fails.
+// Only the values of the keep columns are kept, they are returned for the
+// tables referencing the table.
+func (w *Walker) fillTable(ctx context.Context, table *model.Table, data map[*model.Table]map[string][]interface{}, existing int, keep []string, exec func(sql string, values []interface{}) error, stats *tracker) (map[string][]interface{}, error) {
+	generator, err := newRowGenerator(table, data, existing)
+	if err != nil {
+		return nil, err
+	}
+	stats.start(table, table.TableGenerationSettings.RowsCount)
+
+	type batch struct {
+		sql    string
+		values []interface{}
+		rows   int
+	}
+	batches := make(chan batch, 1)
+	done := make(chan struct{})
+	errs := make(chan error, 1)
+	kept := make(map[string][]interface{}, len(keep))
+
+	// the producer is joined before returning, so kept and stats are not
+	// touched after fillTable returns
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	defer close(done)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer close(batches)
+		count, size := table.TableGenerationSettings.RowsCount, generator.batchSize()
+		for n := 0; n < count; n += size {
+			stmt := generator.insert()
+			rows := 0
+			for i := n; i < n+size && i < count; i++ {
+				row, err := generator.Next()
+				if err != nil {
+					errs <- err
+					return
+				}
+				values := make([]interface{}, 0, len(generator.columns))
+				for _, column := range generator.columns {
+					values = append(values, row[column.Name])
+				}
+				stmt = stmt.Values(values...)
+				for _, column := range keep {
+					kept[column] = append(kept[column], row[column])
+				}
+				rows++
+			}
+			stats.update(table, func(s *TableStats) {
+				s.Generated += rows
+				s.Retries = generator.retries
+			})
+
+			sql, values, err := stmt.PlaceholderFormat(sq.Dollar).ToSql()
+			if err != nil {
+				errs <- err
+				return
+			}
+			select {
+			case batches <- batch{sql: sql, values: values, rows: rows}:
+			case <-done:
+				return
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	for b := range batches {
+		if err := exec(b.sql, b.values); err != nil {
+			return nil, fmt.Errorf("unable to execute query: %w", err)
+		}
+		stats.update(table, func(s *TableStats) {
+			s.Inserted += b.rows
+		})
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	select {
+	case err := <-errs:
+		return nil, err
+	default:
+	}
+
+	return kept, nil
+}
@@ -0,0 +1,101 @@
+package walker
+
+import (
+	"github.com/levtul/tmp/model"
+	"sync"
+	"time"
+)
+
+// TableStats describes the filling of a table, it is reported while the
+// table is filled and returned by FillAllDB.
+type TableStats struct {
+	Schema string `json:"schema"`
+	Table  string `json:"table"`
+	// Rows is the number of rows to generate.
+	Rows      int `json:"rows"`
+	Generated int `json:"generated"`
+	Inserted  int `json:"inserted"`
+	// Retries is the number of rows generated again because they broke
+	// a unique key.
+	Retries  int           `json:"retries"`
+	Duration time.Duration `json:"duration_ns"`
+}
+
+// RowsPerSecond returns the insertion rate of the table.
+func (s TableStats) RowsPerSecond() float64 {
+	if s.Duration <= 0 {
+		return 0
+	}
+	return float64(s.Inserted) / s.Duration.Seconds()
+}
+
+// ETA estimates the time left to insert the rows of the table.
+func (s TableStats) ETA() time.Duration {
+	rate := s.RowsPerSecond()
+	if rate == 0 || s.Inserted >= s.Rows {
+		return 0
+	}
+	return time.Duration(float64(s.Rows-s.Inserted) / rate * float64(time.Second))
+}
+
+// tracker collects statistics of the tables, filled concurrently, and
+// passes them to report one at a time.
+type tracker struct {
+	mu      sync.Mutex
+	stats   map[*model.Table]*TableStats
+	started map[*model.Table]time.Time
+	report  func(stats TableStats)
+}
+
+func newTracker(order []*model.Table, report func(stats TableStats)) *tracker {
+	t := &tracker{
+		stats:   make(map[*model.Table]*TableStats, len(order)),
+		started: make(map[*model.Table]time.Time, len(order)),
+		report:  report,
+	}
+	for _, table := range order {
+		t.stats[table] = &TableStats{Schema: table.Schema, Table: table.Name}
+	}
+
+	return t
+}
+
+// update changes the statistics of the table and reports them.
+func (t *tracker) update(table *model.Table, fn func(stats *TableStats)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	s := t.stats[table]
+	fn(s)
+	if started, ok := t.started[table]; ok {
+		s.Duration = time.Since(started)
+	}
+	if t.report != nil {
+		t.report(*s)
+	}
+}
+
+// start resets the statistics of the table, that is filled again after
+// a rollback to its savepoint.
+func (t *tracker) start(table *model.Table, rows int) {
+	t.mu.Lock()
+	t.started[table] = time.Now()
+	t.mu.Unlock()
+
+	t.update(table, func(s *TableStats) {
+		s.Rows, s.Generated, s.Inserted, s.Retries = rows, 0, 0, 0
+	})
+}
+
+// list returns the statistics in the order of the tables.
+func (t *tracker) list(order []*model.Table) []TableStats {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	res := make([]TableStats, 0, len(order))
+	for _, table := range order {
+		res = append(res, *t.stats[table])
+	}
+
+	return res
+}
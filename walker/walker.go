@@ -1,18 +1,35 @@
 package walker
 
 import (
+	"errors"
 	"fmt"
 	"github.com/auxten/postgresql-parser/pkg/sql/sem/tree"
 	"github.com/levtul/tmp/model"
-	"strconv"
+	"strings"
 )
 
 type Walker struct {
 	Schemas  map[string]*model.Schema
-	Errs     []error
-	Warnings []error
+	Errs     []*model.Diagnostic
+	Warnings []*model.Diagnostic
+
+	searchPath []string
+	refs       []*pendingRef
+}
+
+// pendingRef is a foreign key reference, references are resolved by
+// ResolveReferences after all statements are walked, so tables may be
+// referenced before they are declared. Such references are linked once the
+// table is declared.
+type pendingRef struct {
+	stmt       model.Statement
+	table      *model.Table
+	fk         *model.ForeignKeyConstraint
+	searchPath []string
 }
 
+var defaultSearchPath = []string{"public"}
+
 func NewWalker() *Walker {
 	w := &Walker{
 		Schemas: map[string]*model.Schema{
@@ -23,35 +40,49 @@ func NewWalker() *Walker {
 		},
 	}
 	w.Schemas[""] = w.Schemas["public"]
+	w.searchPath = defaultSearchPath
 
 	return w
 }
 
-func (w *Walker) GetWalkFunc(expr string) func(ctx interface{}, node interface{}) (stop bool) {
+func (w *Walker) GetWalkFunc(stmt model.Statement) func(ctx interface{}, node interface{}) (stop bool) {
 	return func(ctx interface{}, node interface{}) (stop bool) {
 		switch n := node.(type) {
 		case *tree.CreateSchema:
 			if _, ok := w.Schemas[n.Schema]; ok {
-				w.Errs = append(w.Errs, fmt.Errorf("%s: \nschema %s already declared", expr, n.Schema))
+				if n.IfNotExists {
+					return false
+				}
+				w.addError(stmt, model.CodeDuplicate, stmt.Find(n.Schema), fmt.Errorf("schema %s already declared", n.Schema))
 				return false
 			}
 			w.Schemas[n.Schema] = &model.Schema{
 				Name:   n.Schema,
 				Tables: map[string]*model.Table{},
 			}
-		case *tree.CreateTable:
-			s := n.Table.Schema()
-			if s == "" {
-				s = "public"
+		case *tree.SetVar:
+			if n.Name == "search_path" {
+				w.searchPath = searchPath(n.Values)
 			}
-			schema, ok := w.Schemas[s]
-			if !ok {
-				w.Errs = append(w.Errs, fmt.Errorf("%s: \nschema %s not found", expr, s))
+		case *tree.CreateTable:
+			schema, err := w.creationSchema(n.Table.Schema())
+			if err != nil {
+				w.addError(stmt, model.CodeNotFound, stmt.Find(n.Table.Schema()), err)
 				return false
 			}
+			s := schema.Name
 
 			if _, ok := schema.Tables[n.Table.Table()]; ok {
-				w.Errs = append(w.Errs, fmt.Errorf("%s: \ntable %s already declared", expr, n.Table.Table()))
+				if n.IfNotExists {
+					return false
+				}
+				w.addError(stmt, model.CodeDuplicate, stmt.Find(n.Table.Table()), fmt.Errorf("table %s already declared", n.Table.Table()))
+				return false
+			}
+
+			annotations, err := model.ParseTableAnnotations(stmt.Text)
+			if err != nil {
+				w.addError(stmt, model.CodeAnnotation, 0, err)
 				return false
 			}
 
@@ -61,152 +92,349 @@ func (w *Walker) GetWalkFunc(expr string) func(ctx interface{}, node interface{}
 				Columns: map[string]*model.Column{},
 			}
 			schema.Tables[n.Table.Table()] = table
+			w.linkPendingRefs(table)
 
 			n.HoistConstraints()
 
 			for _, def := range n.Defs {
-				switch d := def.(type) {
-				case *tree.ColumnTableDef:
-					table.Columns[string(d.Name)] = &model.Column{
-						Name:    string(d.Name),
-						Type:    d.Type,
-						NotNull: d.Nullable.Nullability == tree.NotNull,
-					}
-					col := table.Columns[string(d.Name)]
-
-					if d.PrimaryKey.IsPrimaryKey {
-						table.PrimaryKey = append(table.PrimaryKey, string(d.Name))
-					}
-
-					if val := model.GetNthGroup(expr, model.GetColumnCommentReg(col.Name), 2); val != "" {
-						gt, err := model.NewGenerationTypeFromString(val, col.Type)
-						if err != nil {
-							w.Errs = append(w.Errs, fmt.Errorf("%s: \n%s", expr, err))
-							return false
-						}
-						col.GenerationType = *gt
-					}
-				case *tree.UniqueConstraintTableDef:
-					if d.PrimaryKey {
-						table.PrimaryKey = make([]string, 0, len(d.Columns))
-						for _, column := range d.Columns {
-							table.PrimaryKey = append(table.PrimaryKey, column.Column.String())
-						}
-					} else {
-						columns := make([]string, 0, len(d.Columns))
-						for _, column := range d.Columns {
-							columns = append(columns, column.Column.String())
-						}
-						if len(columns) == 1 {
-							table.Columns[columns[0]].Unique = true
-						}
-						table.UniqueConstraints = append(table.UniqueConstraints, &columns)
-					}
-				case *tree.ForeignKeyConstraintTableDef:
-					table.ForeignKeyConstraints = append(table.ForeignKeyConstraints, &model.ForeignKeyConstraint{
-						Columns: d.FromCols.ToStrings(),
-						Ref: &model.ForeignKeyRef{
-							Table:       schema.Tables[d.Table.Table()],
-							TableSchema: d.Table.Schema(),
-							TableName:   d.Table.Table(),
-							Columns:     d.ToCols.ToStrings(),
-						},
-					})
-				case *tree.CheckConstraintTableDef:
-					w.Warnings = append(w.Warnings, fmt.Errorf("%s: \ncheck constraints are not supported, program may fail", expr))
+				if d, ok := def.(*tree.ColumnTableDef); ok && !w.addColumn(stmt, table, d) {
+					return false
+				}
+			}
+			for _, def := range n.Defs {
+				if d, ok := def.(tree.ConstraintTableDef); ok && !w.addConstraint(stmt, table, d) {
+					return false
+				}
+			}
+
+			for name, directives := range annotations.Columns {
+				column, ok := table.Columns[name]
+				if !ok {
+					w.addError(stmt, model.CodeNotFound, directives[0].Offset, fmt.Errorf("column %s not found", name))
+					return false
+				}
+
+				if err := model.ApplyColumnDirectives(table, column, directives); err != nil {
+					w.addError(stmt, model.CodeAnnotation, 0, err)
+					return false
 				}
 			}
 
-			if str := model.GetNthGroup(expr, model.CreateTableCommentReg, 1); str != "" {
-				table.TableGenerationSettings = &model.TableGenerationSettings{}
-				table.TableGenerationSettings.RowsCount, _ = strconv.Atoi(str)
+			if err := model.ApplyTableDirectives(table, annotations.Table); err != nil {
+				w.addError(stmt, model.CodeAnnotation, 0, err)
+				return false
 			}
-		case *tree.AlterTable:
-			tableName := n.Table.ToTableName()
-			s := tableName.SchemaName.String()
-			if s == "" || s == `""` {
-				s = "public"
+		case *tree.CommentOnTable:
+			table, ok := w.findTable(stmt, n.Table)
+			if !ok || n.Comment == nil {
+				return false
+			}
+
+			directives, err := model.ParseComment(commentLiteral(stmt, *n.Comment))
+			if err == nil {
+				err = model.ApplyTableDirectives(table, directives)
 			}
-			schema, ok := w.Schemas[s]
+			if err != nil {
+				w.addError(stmt, model.CodeAnnotation, 0, err)
+				return false
+			}
+		case *tree.CommentOnColumn:
+			if n.ColumnItem.TableName == nil {
+				w.addError(stmt, model.CodeNotFound, stmt.Find(string(n.ColumnItem.ColumnName)), fmt.Errorf("table name expected"))
+				return false
+			}
+			table, ok := w.findTable(stmt, n.ColumnItem.TableName)
+			if !ok || n.Comment == nil {
+				return false
+			}
+			column, ok := table.Columns[string(n.ColumnItem.ColumnName)]
 			if !ok {
-				w.Errs = append(w.Errs, fmt.Errorf("%s: \nschema %s not found", expr, s))
+				w.addError(stmt, model.CodeNotFound, stmt.Find(string(n.ColumnItem.ColumnName)), fmt.Errorf("column %s not found", n.ColumnItem.ColumnName.String()))
 				return false
 			}
 
-			table, ok := schema.Tables[string(tableName.TableName)]
+			directives, err := model.ParseComment(commentLiteral(stmt, *n.Comment))
+			if err == nil {
+				err = model.ApplyColumnDirectives(table, column, directives)
+			}
+			if err != nil {
+				w.addError(stmt, model.CodeAnnotation, 0, err)
+				return false
+			}
+		case *tree.AlterTable:
+			if n.IfExists && !w.tableExists(n.Table) {
+				return false
+			}
+			table, ok := w.findTable(stmt, n.Table)
 			if !ok {
-				w.Errs = append(w.Errs, fmt.Errorf("%s: \ntable %s not found", expr, tableName.TableName.String()))
 				return false
 			}
 
 			for _, cmd := range n.Cmds {
-				switch c := cmd.(type) {
-				case *tree.AlterTableAddConstraint:
-					switch d := c.ConstraintDef.(type) {
-					case *tree.UniqueConstraintTableDef:
-						if d.PrimaryKey {
-							table.PrimaryKey = make([]string, 0, len(d.Columns))
-							for _, column := range d.Columns {
-								table.PrimaryKey = append(table.PrimaryKey, column.Column.String())
-							}
-						} else {
-							columns := make([]string, 0, len(d.Columns))
-							for _, column := range d.Columns {
-								columns = append(columns, column.Column.String())
-							}
-							if len(columns) == 1 {
-								table.Columns[columns[0]].Unique = true
-							}
-							table.UniqueConstraints = append(table.UniqueConstraints, &columns)
-						}
-					case *tree.ForeignKeyConstraintTableDef:
-						s := d.Table.Schema()
-						if s == "" {
-							s = "public"
-						}
-						toSchema, ok := w.Schemas[s]
-						if !ok {
-							w.Errs = append(w.Errs, fmt.Errorf("%s: \nschema %s not found", expr, d.Table.Schema()))
-							return false
-						}
-
-						toTable, ok := toSchema.Tables[d.Table.Table()]
-						if !ok {
-							w.Errs = append(w.Errs, fmt.Errorf("%s: \ntable %s not found", expr, d.Table.Table()))
-							return false
-						}
-
-						table.ForeignKeyConstraints = append(table.ForeignKeyConstraints, &model.ForeignKeyConstraint{
-							Columns: d.FromCols.ToStrings(),
-							Ref: &model.ForeignKeyRef{
-								Table:       toTable,
-								TableSchema: toTable.Schema,
-								TableName:   toTable.Name,
-								Columns:     d.ToCols.ToStrings(),
-							},
-						})
-					case *tree.CheckConstraintTableDef:
-						w.Warnings = append(w.Warnings, fmt.Errorf("%s: \ncheck constraints are not supported, program may fail", expr))
-					}
-				case *tree.AlterTableAddColumn:
-					w.Warnings = append(w.Warnings, fmt.Errorf("%s: \n\"ADD COLUMN %s\" must be in \"CREATE TABLE\" expression, not in \"ALTER TABLE\" column will be ignored", expr, c.ColumnDef.Name.String()))
-				case *tree.AlterTableSetNotNull:
-					column, ok := table.Columns[c.Column.String()]
-					if !ok {
-						w.Errs = append(w.Errs, fmt.Errorf("%s: \ncolumn %s not found", expr, c.Column.String()))
-						return false
-					}
-
-					column.NotNull = true
-				case *tree.AlterTableAlterPrimaryKey:
-					table.PrimaryKey = make([]string, 0, len(c.Columns))
-					for _, column := range c.Columns {
-						table.PrimaryKey = append(table.PrimaryKey, column.Column.String())
-					}
+				if !w.alterTable(stmt, table, cmd) {
+					return false
 				}
 			}
+		case *tree.DropTable:
+			for i := range n.Names {
+				name := n.Names[i].ToUnresolvedObjectName()
+				if n.IfExists && !w.tableExists(name) {
+					continue
+				}
+				table, ok := w.findTable(stmt, name)
+				if !ok || !w.dropTable(stmt, table, n.DropBehavior == tree.DropCascade) {
+					return false
+				}
+			}
+		case *tree.DropIndex:
+			w.dropIndex(n)
+		case *tree.RenameTable:
+			if n.IsView || n.IsSequence || n.IfExists && !w.tableExists(n.Name) {
+				return false
+			}
+			table, ok := w.findTable(stmt, n.Name)
+			if !ok {
+				return false
+			}
+
+			w.renameTable(stmt, table, n.NewName)
 		}
 
 		return false
 	}
 }
+
+func (w *Walker) findTable(stmt model.Statement, name *tree.UnresolvedObjectName) (*model.Table, bool) {
+	tableName := name.ToTableName()
+	table, err := w.lookupTable(tableName.Schema(), tableName.Table(), w.searchPath)
+	if err != nil {
+		w.addError(stmt, model.CodeNotFound, stmt.Find(tableName.Table()), err)
+		return nil, false
+	}
+
+	return table, true
+}
+
+// lookupTable finds a table by its name, names without schema are looked
+// up in the schemas of the search path.
+func (w *Walker) lookupTable(schemaName, name string, searchPath []string) (*model.Table, error) {
+	if schemaName != "" {
+		schema, ok := w.Schemas[schemaName]
+		if !ok {
+			return nil, fmt.Errorf("schema %s not found", schemaName)
+		}
+		table, ok := schema.Tables[name]
+		if !ok {
+			return nil, fmt.Errorf("table %s.%s not found", schemaName, name)
+		}
+		return table, nil
+	}
+
+	for _, s := range searchPath {
+		if schema, ok := w.Schemas[s]; ok {
+			if table, ok := schema.Tables[name]; ok {
+				return table, nil
+			}
+		}
+	}
+
+	return nil, fmt.Errorf("table %s not found in search path %s", name, strings.Join(searchPath, ", "))
+}
+
+// creationSchema returns the schema a new table is created in: the given
+// one or the first existing schema of the search path.
+func (w *Walker) creationSchema(schemaName string) (*model.Schema, error) {
+	if schemaName != "" {
+		schema, ok := w.Schemas[schemaName]
+		if !ok {
+			return nil, fmt.Errorf("schema %s not found", schemaName)
+		}
+		return schema, nil
+	}
+
+	for _, s := range w.searchPath {
+		if schema, ok := w.Schemas[s]; ok {
+			return schema, nil
+		}
+	}
+
+	return nil, fmt.Errorf("no schema of search path %s exists to create table in", strings.Join(w.searchPath, ", "))
+}
+
+func (w *Walker) addForeignKey(stmt model.Statement, table *model.Table, d *tree.ForeignKeyConstraintTableDef) {
+	fk := &model.ForeignKeyConstraint{
+		Name:    string(d.Name),
+		Columns: d.FromCols.ToStrings(),
+		Ref: &model.ForeignKeyRef{
+			TableSchema: d.Table.Schema(),
+			TableName:   d.Table.Table(),
+			Columns:     d.ToCols.ToStrings(),
+		},
+	}
+	if fk.Name == "" {
+		fk.Name = model.ConstraintName(table.Name, fk.Columns, "fkey")
+	}
+	// tables declared so far are linked right away to follow their renames
+	if ref, err := w.lookupTable(fk.Ref.TableSchema, fk.Ref.TableName, w.searchPath); err == nil {
+		fk.Ref.Table = ref
+	}
+	table.ForeignKeyConstraints = append(table.ForeignKeyConstraints, fk)
+	w.refs = append(w.refs, &pendingRef{stmt: stmt, table: table, fk: fk, searchPath: w.searchPath})
+}
+
+// linkPendingRefs links foreign keys declared before the table to it, so
+// they follow its later renames and are checked when it is dropped.
+func (w *Walker) linkPendingRefs(table *model.Table) {
+	for _, p := range w.refs {
+		if p.fk.Ref.Table != nil {
+			continue
+		}
+		if ref, err := w.lookupTable(p.fk.Ref.TableSchema, p.fk.Ref.TableName, p.searchPath); err == nil && ref == table {
+			p.fk.Ref.Table = table
+		}
+	}
+}
+
+// ResolveReferences links foreign keys to the tables they reference. It must
+// be called after all statements are walked.
+func (w *Walker) ResolveReferences() {
+	for _, p := range w.refs {
+		if !w.hasForeignKey(p.table, p.fk) {
+			continue
+		}
+
+		ref := p.fk.Ref
+		table := ref.Table
+		if table == nil {
+			var err error
+			table, err = w.lookupTable(ref.TableSchema, ref.TableName, p.searchPath)
+			if err != nil {
+				w.addError(p.stmt, model.CodeNotFound, p.stmt.Find(ref.TableName), fmt.Errorf("foreign key of table %s.%s: %w", p.table.Schema, p.table.Name, err))
+				continue
+			}
+		}
+
+		ref.Table = table
+		ref.TableSchema = table.Schema
+		ref.TableName = table.Name
+		if len(ref.Columns) == 0 {
+			ref.Columns = table.PrimaryKey
+		}
+
+		if err := validateForeignKey(p.table, p.fk); err != nil {
+			w.addError(p.stmt, model.CodeForeignKey, p.stmt.Find(ref.TableName), fmt.Errorf("foreign key of table %s.%s: %w", p.table.Schema, p.table.Name, err))
+		}
+	}
+	w.refs = nil
+}
+
+// hasForeignKey reports whether the foreign key was not dropped together
+// with its table or by ALTER TABLE.
+func (w *Walker) hasForeignKey(table *model.Table, fk *model.ForeignKeyConstraint) bool {
+	if schema, ok := w.Schemas[table.Schema]; !ok || schema.Tables[table.Name] != table {
+		return false
+	}
+	for _, f := range table.ForeignKeyConstraints {
+		if f == fk {
+			return true
+		}
+	}
+
+	return false
+}
+
+// validateForeignKey checks that the referencing and referenced columns
+// exist and match in count and type, and that the referenced columns are
+// the primary key or a unique key.
+func validateForeignKey(table *model.Table, fk *model.ForeignKeyConstraint) error {
+	ref := fk.Ref
+	if len(ref.Columns) == 0 {
+		return fmt.Errorf("referenced table %s.%s has no primary key, specify referenced columns", ref.TableSchema, ref.TableName)
+	}
+	if len(fk.Columns) != len(ref.Columns) {
+		return fmt.Errorf("number of referencing columns (%d) does not match number of referenced columns (%d) of table %s.%s",
+			len(fk.Columns), len(ref.Columns), ref.TableSchema, ref.TableName)
+	}
+
+	for i, name := range fk.Columns {
+		column, ok := table.Columns[name]
+		if !ok {
+			return fmt.Errorf("column %s not found", name)
+		}
+		refColumn, ok := ref.Table.Columns[ref.Columns[i]]
+		if !ok {
+			return fmt.Errorf("column %s not found in table %s.%s", ref.Columns[i], ref.TableSchema, ref.TableName)
+		}
+		if column.Type.Family() != refColumn.Type.Family() {
+			return fmt.Errorf("column %s of type %s cannot reference column %s.%s.%s of type %s",
+				name, column.Type.SQLStandardName(), ref.TableSchema, ref.TableName, refColumn.Name, refColumn.Type.SQLStandardName())
+		}
+	}
+
+	for _, key := range ref.Table.Keys() {
+		if key.Plain() && sameColumns(key.Columns, ref.Columns) {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("there is no unique constraint matching columns (%s) of referenced table %s.%s",
+		strings.Join(ref.Columns, ", "), ref.TableSchema, ref.TableName)
+}
+
+// sameColumns reports whether a and b have the same columns in any order.
+func sameColumns(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for _, name := range a {
+		if !contains(b, name) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// searchPath returns schema names of SET search_path values.
+func searchPath(values tree.Exprs) []string {
+	var res []string
+	for _, value := range values {
+		switch v := value.(type) {
+		case tree.DefaultVal:
+			return defaultSearchPath
+		case *tree.UnresolvedName:
+			res = append(res, v.Parts[0])
+		case *tree.StrVal:
+			for _, s := range strings.Split(v.RawString(), ",") {
+				res = append(res, strings.Trim(strings.TrimSpace(s), `"`))
+			}
+		}
+	}
+
+	return res
+}
+
+// addError reports an error at the byte offset of the statement, errors
+// that know their position (*model.SourceError) override the offset.
+func (w *Walker) addError(stmt model.Statement, code string, offset int, err error) {
+	var sourceErr *model.SourceError
+	if errors.As(err, &sourceErr) {
+		offset = sourceErr.Offset
+	}
+
+	w.Errs = append(w.Errs, stmt.Diagnostic(model.SeverityError, code, offset, err.Error()))
+}
+
+func (w *Walker) addWarning(stmt model.Statement, code string, offset int, message string) {
+	w.Warnings = append(w.Warnings, stmt.Diagnostic(model.SeverityWarning, code, offset, message))
+}
+
+// commentLiteral locates the comment string of COMMENT ON in the statement.
+func commentLiteral(stmt model.Statement, comment string) model.Comment {
+	offset := strings.LastIndex(stmt.Text, comment)
+	if offset < 0 {
+		offset = 0
+	}
+
+	return model.Comment{Text: comment, Offset: offset}
+}
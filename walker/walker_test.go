@@ -0,0 +1,173 @@
+package walker_test
+
+import (
+	"errors"
+	"github.com/levtul/tmp/domain"
+	"github.com/levtul/tmp/model"
+	"github.com/levtul/tmp/walker"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func walk(t *testing.T, text string) (*walker.Walker, error) {
+	t.Helper()
+	return domain.Walk(domain.Source{File: "schema.sql", Text: text})
+}
+
+func table(t *testing.T, w *walker.Walker, schema, name string) *model.Table {
+	t.Helper()
+	s, ok := w.Schemas[schema]
+	if !ok {
+		t.Fatalf("schema %s not found", schema)
+	}
+	tbl, ok := s.Tables[name]
+	if !ok {
+		t.Fatalf("table %s.%s not found", schema, name)
+	}
+	return tbl
+}
+
+func refs(tbl *model.Table) []string {
+	var res []string
+	for _, fk := range tbl.ForeignKeyConstraints {
+		res = append(res, strings.Join(fk.Columns, ",")+" -> "+fk.Ref.Table.Schema+"."+fk.Ref.Table.Name+"("+strings.Join(fk.Ref.Columns, ",")+")")
+	}
+	return res
+}
+
+func TestWalkSchemas(t *testing.T) {
+	source, err := domain.ReadSource("../examples/valid/schemas")
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, err := domain.Walk(source)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		schema, table string
+		columns       []string
+		refs          []string
+	}{
+		{schema: "shop", table: "customers", columns: []string{"Name", "group", "id"}},
+		{schema: "shop", table: "Order Items", columns: []string{"customer_id", "id", "order", "select"},
+			refs: []string{"customer_id -> shop.customers(id)"}},
+		{schema: "Billing", table: "Invoices", columns: []string{"ID", "Total", "customer_id"},
+			refs: []string{"customer_id -> shop.customers(id)"}},
+		{schema: "shop", table: "reviews", columns: []string{"id", "invoice_id", "product_id"},
+			refs: []string{"product_id -> shop.products(id)", "invoice_id -> Billing.Invoices(ID)"}},
+		{schema: "shop", table: "products", columns: []string{"id", "title"}},
+	}
+	for _, tt := range tests {
+		tbl := table(t, w, tt.schema, tt.table)
+		var columns []string
+		for name := range tbl.Columns {
+			columns = append(columns, name)
+		}
+		sort.Strings(columns)
+		if !reflect.DeepEqual(columns, tt.columns) {
+			t.Errorf("%s.%s: columns %q, expected %q", tt.schema, tt.table, columns, tt.columns)
+		}
+		if got := refs(tbl); !reflect.DeepEqual(got, tt.refs) {
+			t.Errorf("%s.%s: foreign keys %q, expected %q", tt.schema, tt.table, got, tt.refs)
+		}
+	}
+	if _, ok := w.Schemas["public"].Tables["products"]; ok {
+		t.Errorf("products is created in public instead of the search_path schema")
+	}
+	if !table(t, w, "Billing", "Invoices").Columns["Total"].NotNull {
+		t.Errorf("Billing.Invoices.Total is not NOT NULL")
+	}
+}
+
+func TestWalkSearchPath(t *testing.T) {
+	w, err := walk(t, `
+CREATE SCHEMA a;
+CREATE SCHEMA b;
+CREATE TABLE b.parents (id INT PRIMARY KEY);
+CREATE TABLE public.parents (id INT PRIMARY KEY);
+SET search_path TO a, b;
+CREATE TABLE children (id INT PRIMARY KEY, parent_id INT REFERENCES parents (id));
+SET search_path = public;
+CREATE TABLE others (id INT PRIMARY KEY, parent_id INT REFERENCES parents (id));
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := refs(table(t, w, "a", "children")), []string{"parent_id -> b.parents(id)"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("a.children: foreign keys %q, expected %q", got, want)
+	}
+	if got, want := refs(table(t, w, "public", "others")), []string{"parent_id -> public.parents(id)"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("public.others: foreign keys %q, expected %q", got, want)
+	}
+}
+
+func TestWalkForwardReferences(t *testing.T) {
+	tests := []struct {
+		name string
+		sql  string
+		refs []string
+		code string
+	}{
+		{
+			name: "declared below",
+			sql: `CREATE TABLE children (id INT PRIMARY KEY, parent_id INT REFERENCES parents (id));
+CREATE TABLE parents (id INT PRIMARY KEY);`,
+			refs: []string{"parent_id -> public.parents(id)"},
+		},
+		{
+			name: "renamed after declaration",
+			sql: `CREATE TABLE children (id INT PRIMARY KEY, parent_id INT REFERENCES parents (id));
+CREATE TABLE parents (id INT PRIMARY KEY);
+ALTER TABLE parents RENAME TO people;`,
+			refs: []string{"parent_id -> public.people(id)"},
+		},
+		{
+			name: "declared by renaming",
+			sql: `CREATE TABLE children (id INT PRIMARY KEY, parent_id INT REFERENCES parents (id));
+CREATE TABLE people (id INT PRIMARY KEY);
+ALTER TABLE people RENAME TO parents;`,
+			refs: []string{"parent_id -> public.parents(id)"},
+		},
+		{
+			name: "dropped without cascade",
+			sql: `CREATE TABLE children (id INT PRIMARY KEY, parent_id INT REFERENCES parents (id));
+CREATE TABLE parents (id INT PRIMARY KEY);
+DROP TABLE parents;`,
+			code: model.CodeForeignKey,
+		},
+		{
+			name: "never declared",
+			sql:  `CREATE TABLE children (id INT PRIMARY KEY, parent_id INT REFERENCES parents (id));`,
+			code: model.CodeNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, err := walk(t, tt.sql)
+			if tt.code != "" {
+				var diagnostics model.Diagnostics
+				if !errors.As(err, &diagnostics) {
+					t.Fatalf("expected diagnostics, got %v", err)
+				}
+				for _, d := range diagnostics {
+					if d.Code == tt.code {
+						return
+					}
+				}
+				t.Fatalf("expected %s diagnostic, got %v", tt.code, err)
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := refs(table(t, w, "public", "children")); !reflect.DeepEqual(got, tt.refs) {
+				t.Errorf("foreign keys %q, expected %q", got, tt.refs)
+			}
+		})
+	}
+}